@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/service/pricingService"
+	"github.com/AhegaoHD/WBT/internal/service/rulesService"
+	"github.com/AhegaoHD/WBT/internal/simulator"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+func main() {
+	configPath := flag.String("config", "config/config.toml", "path to config")
+	games := flag.Int("games", 1000, "number of games per strategy")
+	loaders := flag.Int("loaders", 5, "number of loaders hired in each game")
+	seed := flag.Int64("seed", 1, "seed of the first game, game i uses seed+i")
+	strategies := flag.String("strategies", strings.Join([]string{simulator.StrategyGreedy, simulator.StrategyCheapest, simulator.StrategyRandom}, ","), "comma-separated customer strategies")
+	flag.Parse()
+
+	cfg, err := config.Parse(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rules, err := rulesService.NewRegistry(cfg.Game, cfg.CargoTypes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pricer, err := pricingService.NewPricer(cfg.Pricing)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "strategy\tgames\twin rate\tavg capital\tavg capital left\t%s\t\n", strings.Join(simulator.CompletionLabels(), "\t"))
+
+	for _, name := range strings.Split(*strategies, ",") {
+		strategy, err := simulator.NewStrategy(strings.TrimSpace(name))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Все стратегии играют одни и те же партии
+		stats := simulator.NewStats()
+		for i := 0; i < *games; i++ {
+			game, err := simulator.NewGame(ctx, cfg, rules, pricer, *loaders, *seed+int64(i))
+			if err != nil {
+				log.Fatalf("game %d: %v", i, err)
+			}
+			result, err := game.Play(ctx, strategy)
+			if err != nil {
+				log.Fatalf("game %d: %v", i, err)
+			}
+			stats.Add(result)
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%.1f%%\t%.0f\t%.0f\t", strings.TrimSpace(name), stats.Games, stats.WinRate()*100, stats.AvgCapital(), stats.AvgCapitalLeft())
+		for _, n := range stats.Completion {
+			fmt.Fprintf(w, "%d\t", n)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
@@ -1,51 +1,326 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"time"
 
+	"github.com/AhegaoHD/WBT/pkg/logger"
 	"github.com/BurntSushi/toml"
 )
 
 type (
 	Config struct {
-		App        App        `toml:"Application"`
-		Db         Db         `toml:"DB"`
-		HttpServer HttpServer `toml:"HttpServer"`
-		SecretJWT  string     `env:"SecretJWT"`
+		App         App         `toml:"Application"`
+		Db          Db          `toml:"DB"`
+		HttpServer  HttpServer  `toml:"HttpServer"`
+		Log         Log         `toml:"Log"`
+		Game        Game        `toml:"Game"`
+		CargoTypes  []CargoType `toml:"CargoTypes"`
+		Damage      Damage      `toml:"Damage"`
+		Progression Progression `toml:"Progression"`
+		Pricing     Pricing     `toml:"Pricing"`
+		SecretJWT   string      `env:"SecretJWT"`
 	}
 
 	App struct {
 		Name    string `toml:"Name"`
 		Version string `toml:"Version"`
+		// TimeFactor - во сколько раз игровое время идет быстрее реального с создания игры;
+		// 0 или 1 - реальное время. Замедлять время нельзя.
+		TimeFactor float64 `toml:"TimeFactor"`
 	}
 
 	Db struct {
+		// Backend - хранилище: postgres или memory (данные живут до остановки приложения).
+		Backend     string `toml:"Backend"`
 		Name        string `toml:"Name"`
 		Host        string `toml:"Host"`
 		Port        string `toml:"Port"`
 		Schema      string `toml:"Schema"`
 		MaxPoolSize int    `toml:"MaxPoolSize"`
+		// TxRetries - сколько раз повторять транзакцию после deadlock или serialization failure.
+		TxRetries int `toml:"TxRetries"`
+		// TxRetryDelay - базовая пауза перед повтором, растет вдвое с каждой попыткой.
+		TxRetryDelay time.Duration `toml:"TxRetryDelay"`
 
 		User     string `env:"DBUSER"`
 		Password string `env:"DBPASSWORD"`
 	}
 
+	Log struct {
+		// Level - минимальный уровень записей: debug, info, warn или error.
+		Level string `toml:"Level"`
+		// Format - json для сбора логов или console для чтения в терминале.
+		Format string `toml:"Format"`
+	}
+
 	HttpServer struct {
 		ReadTimeout     *time.Duration `toml:"ReadTimeout"`
 		WriteTimeout    *time.Duration `toml:"WriteTimeout"`
 		Addr            string         `toml:"Addr"`
 		ShutdownTimeout *time.Duration `toml:"ShutdownTimeout"`
+		// AdminAddr - порт служебного сервера с /metrics. Пустой - служебный сервер не запускается.
+		AdminAddr string `toml:"AdminAddr"`
+	}
+
+	// CargoType - правила допуска грузчиков к задачам с грузом этого типа.
+	CargoType struct {
+		Name               string `toml:"Name"`
+		ForbidDrunk        bool   `toml:"ForbidDrunk"`
+		MinLoaderMaxWeight int    `toml:"MinLoaderMaxWeight"`
+		MaxLoaderFatigue   *int   `toml:"MaxLoaderFatigue"`
+	}
+
+	// Damage - модель риска порчи груза пьяными или уставшими грузчиками.
+	Damage struct {
+		DrunkProbability   float64 `toml:"DrunkProbability"`
+		FatigueThreshold   int     `toml:"FatigueThreshold"`
+		FatigueProbability float64 `toml:"FatigueProbability"`
+		Penalty            int     `toml:"Penalty"`
+		ChargeTo           string  `toml:"ChargeTo"`
+		// Seed - seed порчи груза, 0 - выводится из seed игры. Кости каждого рейса зависят от seed, задачи и ее версии.
+		Seed int64 `toml:"Seed"`
 	}
 )
 
+type (
+	// Game - экономика игры: диапазоны случайных значений при регистрации
+	// и рост усталости грузчиков за рейс.
+	Game struct {
+		Capital         Range `toml:"Capital"`
+		TaskCount       Range `toml:"TaskCount"`
+		TaskWeight      Range `toml:"TaskWeight"`
+		LoaderMaxWeight Range `toml:"LoaderMaxWeight"`
+		LoaderSalary    Range `toml:"LoaderSalary"`
+		LoaderFatigue   Range `toml:"LoaderFatigue"`
+		DrunkPercent    int   `toml:"DrunkPercent"`
+		FatigueSober    int   `toml:"FatigueSober"`
+		FatigueDrunk    int   `toml:"FatigueDrunk"`
+		// Ruleset - набор правил для заказчиков, не выбравших его при регистрации.
+		Ruleset string `toml:"Ruleset"`
+		// Seed - seed новой игры, 0 - по текущему времени. Seed уже созданной игры хранится в базе.
+		Seed int64 `toml:"Seed"`
+	}
+
+	// Range - диапазон целых чисел, включая границы.
+	Range struct {
+		Min int `toml:"Min"`
+		Max int `toml:"Max"`
+	}
+
+	// Pricing - выбор и параметры формулы цены грузчика за рейс.
+	Pricing struct {
+		Model           string  `toml:"Model"`
+		DemandFactor    float64 `toml:"DemandFactor"`
+		FatigueDiscount float64 `toml:"FatigueDiscount"`
+		LevelPremium    float64 `toml:"LevelPremium"`
+		NightMultiplier float64 `toml:"NightMultiplier"`
+		NightStartHour  int     `toml:"NightStartHour"`
+		NightEndHour    int     `toml:"NightEndHour"`
+	}
+
+	// Progression - опыт грузчиков и уровни, которые он открывает.
+	Progression struct {
+		ExperiencePerTask int     `toml:"ExperiencePerTask"`
+		Levels            []Level `toml:"Levels"`
+	}
+
+	// Level - порог опыта и прибавки, которые грузчик получает при его достижении.
+	Level struct {
+		Experience     int `toml:"Experience"`
+		MaxWeightBonus int `toml:"MaxWeightBonus"`
+		SalaryBonus    int `toml:"SalaryBonus"`
+	}
+)
+
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+const (
+	defaultTxRetries    = 3
+	defaultTxRetryDelay = 10 * time.Millisecond
+)
+
+const (
+	ChargeToCustomer = "customer"
+	ChargeToLoader   = "loader"
+)
+
+// DefaultGame - экономика, действовавшая до появления секции [Game].
+func DefaultGame() Game {
+	return Game{
+		Capital:         Range{Min: 10000, Max: 100000},
+		TaskCount:       Range{Min: 1, Max: 5},
+		TaskWeight:      Range{Min: 10, Max: 80},
+		LoaderMaxWeight: Range{Min: 5, Max: 30},
+		LoaderSalary:    Range{Min: 10000, Max: 30000},
+		LoaderFatigue:   Range{Min: 0, Max: 100},
+		DrunkPercent:    50,
+		FatigueSober:    20,
+		FatigueDrunk:    50,
+		Ruleset:         "default",
+	}
+}
+
+// DefaultDamage - порча груза выключена, как до появления секции [Damage].
+func DefaultDamage() Damage {
+	return Damage{FatigueThreshold: 100, ChargeTo: ChargeToCustomer}
+}
+
+// Pick возвращает случайное число из диапазона; intn - источник случайности вида rand.Intn.
+func (r Range) Pick(intn func(n int) int) int {
+	return intn(r.Max-r.Min+1) + r.Min
+}
+
+func (r Range) validate(name string, min, max int) error {
+	if r.Min > r.Max {
+		return fmt.Errorf("config - Game: %s.Min > %s.Max", name, name)
+	}
+	if r.Min < min || r.Max > max {
+		return fmt.Errorf("config - Game: %s must be in %d..%d", name, min, max)
+	}
+	return nil
+}
+
 func Parse(path string) (*Config, error) {
-	var conf Config
+	conf := Config{
+		Db:     Db{TxRetries: defaultTxRetries, TxRetryDelay: defaultTxRetryDelay},
+		Game:   DefaultGame(),
+		Damage: DefaultDamage(),
+	}
 	_, err := toml.DecodeFile(path, &conf)
 	if err != nil {
 		return nil, err
 	}
 
+	err = conf.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	conf.SecretJWT = os.Getenv("SECRETJWT")
 	return &conf, nil
 }
+
+func (c *Config) validate() error {
+	if c.App.TimeFactor < 0 || c.App.TimeFactor > 0 && c.App.TimeFactor < 1 {
+		return errors.New("config - Application: TimeFactor must be 0 or >= 1")
+	}
+	if c.Db.Backend == "" {
+		c.Db.Backend = BackendPostgres
+	}
+	if c.Db.Backend != BackendPostgres && c.Db.Backend != BackendMemory {
+		return fmt.Errorf("config - DB: Backend must be %q or %q", BackendPostgres, BackendMemory)
+	}
+	if c.Db.TxRetries < 0 || c.Db.TxRetryDelay < 0 {
+		return errors.New("config - DB: TxRetries and TxRetryDelay must not be negative")
+	}
+	if c.HttpServer.AdminAddr != "" && c.HttpServer.AdminAddr == c.HttpServer.Addr {
+		return errors.New("config - HttpServer: AdminAddr must differ from Addr")
+	}
+	if c.Log.Format == "" {
+		c.Log.Format = logger.FormatJSON
+	}
+	if c.Log.Format != logger.FormatJSON && c.Log.Format != logger.FormatConsole {
+		return fmt.Errorf("config - Log: Format must be %q or %q", logger.FormatJSON, logger.FormatConsole)
+	}
+	if _, err := logger.ParseLevel(c.Log.Level); err != nil {
+		return fmt.Errorf("config - Log: %w", err)
+	}
+	err := c.Game.validate()
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]struct{}, len(c.CargoTypes))
+	for _, cargo := range c.CargoTypes {
+		if cargo.Name == "" {
+			return errors.New("config - CargoTypes: empty name")
+		}
+		if _, ok := names[cargo.Name]; ok {
+			return fmt.Errorf("config - CargoTypes: duplicate name %q", cargo.Name)
+		}
+		names[cargo.Name] = struct{}{}
+		if cargo.MinLoaderMaxWeight < 0 {
+			return fmt.Errorf("config - CargoTypes %q: MinLoaderMaxWeight < 0", cargo.Name)
+		}
+		if cargo.MaxLoaderFatigue != nil && (*cargo.MaxLoaderFatigue < 0 || *cargo.MaxLoaderFatigue > 100) {
+			return fmt.Errorf("config - CargoTypes %q: MaxLoaderFatigue must be in 0..100", cargo.Name)
+		}
+	}
+
+	if c.Damage.DrunkProbability < 0 || c.Damage.DrunkProbability > 1 {
+		return errors.New("config - Damage: DrunkProbability must be in 0..1")
+	}
+	if c.Damage.FatigueThreshold < 1 || c.Damage.FatigueThreshold > 100 {
+		return errors.New("config - Damage: FatigueThreshold must be in 1..100")
+	}
+	if c.Damage.FatigueProbability < 0 || c.Damage.FatigueProbability > 1 {
+		return errors.New("config - Damage: FatigueProbability must be in 0..1")
+	}
+	if c.Damage.Penalty < 0 {
+		return errors.New("config - Damage: Penalty < 0")
+	}
+	if c.Damage.ChargeTo == "" {
+		c.Damage.ChargeTo = ChargeToCustomer
+	}
+	if c.Damage.ChargeTo != ChargeToCustomer && c.Damage.ChargeTo != ChargeToLoader {
+		return fmt.Errorf("config - Damage: ChargeTo must be %q or %q", ChargeToCustomer, ChargeToLoader)
+	}
+
+	if c.Progression.ExperiencePerTask < 0 {
+		return errors.New("config - Progression: ExperiencePerTask < 0")
+	}
+	for i, level := range c.Progression.Levels {
+		if i > 0 && level.Experience <= c.Progression.Levels[i-1].Experience {
+			return errors.New("config - Progression: Levels must be sorted by Experience")
+		}
+		if level.Experience <= 0 || level.MaxWeightBonus < 0 || level.SalaryBonus < 0 {
+			return fmt.Errorf("config - Progression: Levels[%d] has negative values", i)
+		}
+	}
+
+	if c.Pricing.DemandFactor < 0 || c.Pricing.LevelPremium < 0 || c.Pricing.NightMultiplier < 0 {
+		return errors.New("config - Pricing: factors must not be negative")
+	}
+	if c.Pricing.FatigueDiscount < 0 || c.Pricing.FatigueDiscount > 1 {
+		return errors.New("config - Pricing: FatigueDiscount must be in 0..1")
+	}
+	if c.Pricing.NightStartHour < 0 || c.Pricing.NightStartHour > 23 || c.Pricing.NightEndHour < 0 || c.Pricing.NightEndHour > 23 {
+		return errors.New("config - Pricing: night hours must be in 0..23")
+	}
+	return nil
+}
+
+func (g *Game) validate() error {
+	ranges := []struct {
+		name     string
+		r        Range
+		min, max int
+	}{
+		{"Capital", g.Capital, 0, math.MaxInt32},
+		{"TaskCount", g.TaskCount, 1, 1000},
+		{"TaskWeight", g.TaskWeight, 1, math.MaxInt32},
+		{"LoaderMaxWeight", g.LoaderMaxWeight, 1, math.MaxInt32},
+		{"LoaderSalary", g.LoaderSalary, 0, math.MaxInt32},
+		{"LoaderFatigue", g.LoaderFatigue, 0, 100},
+	}
+	for _, v := range ranges {
+		err := v.r.validate(v.name, v.min, v.max)
+		if err != nil {
+			return err
+		}
+	}
+	if g.DrunkPercent < 0 || g.DrunkPercent > 100 {
+		return errors.New("config - Game: DrunkPercent must be in 0..100")
+	}
+	if g.FatigueSober < 0 || g.FatigueDrunk < 0 {
+		return errors.New("config - Game: fatigue increments must not be negative")
+	}
+	return nil
+}
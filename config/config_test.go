@@ -0,0 +1,42 @@
+package config_test
+
+import (
+	"github.com/AhegaoHD/WBT/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseWithoutDamageSection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(path, []byte("[Application]\nName = \"WBT\"\n\n[DB]\nBackend = \"memory\"\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Damage != config.DefaultDamage() {
+		t.Errorf("damage = %+v, want %+v", cfg.Damage, config.DefaultDamage())
+	}
+}
+
+func TestParseKeepsDamageDefaultsForMissingKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(path, []byte("[Damage]\nDrunkProbability = 0.5\nPenalty = 100\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config.DefaultDamage()
+	want.DrunkProbability, want.Penalty = 0.5, 100
+	if cfg.Damage != want {
+		t.Errorf("damage = %+v, want %+v", cfg.Damage, want)
+	}
+}
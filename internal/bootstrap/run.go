@@ -5,47 +5,79 @@ import (
 	"fmt"
 	"github.com/AhegaoHD/WBT/config"
 	"github.com/AhegaoHD/WBT/internal/controller/http/authController"
+	"github.com/AhegaoHD/WBT/internal/controller/http/boardController"
+	"github.com/AhegaoHD/WBT/internal/controller/http/contractController"
 	"github.com/AhegaoHD/WBT/internal/controller/http/middleware"
+	"github.com/AhegaoHD/WBT/internal/controller/http/reviewController"
 	httpController "github.com/AhegaoHD/WBT/internal/controller/http/userController"
-	"github.com/AhegaoHD/WBT/internal/repository/customerRepository"
-	"github.com/AhegaoHD/WBT/internal/repository/loaderRepository"
-	"github.com/AhegaoHD/WBT/internal/repository/taskRepository"
-	"github.com/AhegaoHD/WBT/internal/repository/userRepository"
+	"github.com/AhegaoHD/WBT/internal/service/contractService"
 	"github.com/AhegaoHD/WBT/internal/service/jwtService"
+	"github.com/AhegaoHD/WBT/internal/service/pricingService"
+	"github.com/AhegaoHD/WBT/internal/service/reviewService"
+	"github.com/AhegaoHD/WBT/internal/service/rulesService"
 	"github.com/AhegaoHD/WBT/internal/service/taskService"
 	"github.com/AhegaoHD/WBT/internal/service/userService"
+	"github.com/AhegaoHD/WBT/pkg/clock"
 	"github.com/AhegaoHD/WBT/pkg/httpserver"
-	"github.com/AhegaoHD/WBT/pkg/postgres"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/AhegaoHD/WBT/pkg/random"
 	"github.com/gorilla/mux"
-	"log"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
 func Run(cfg *config.Config) {
-	pg, err := postgres.New(postgres.GetConnString(&cfg.Db), postgres.MaxPoolSize(cfg.Db.MaxPoolSize))
+	l := logger.New(cfg.Log.Level, cfg.Log.Format)
+	// Записи вне HTTP-запросов (запуск, фоновые задачи) идут в тот же лог
+	logger.SetDefault(l)
+
+	repos, closeStorage, err := newStorage(cfg)
 	if err != nil {
-		log.Fatal("APP - START - POSTGRES INI PROBLEM: %v", err)
+		l.Fatal("APP - START - STORAGE INI PROBLEM: %v", err)
 	}
-	defer pg.Close()
+	defer closeStorage()
+	l.Info("storage backend: %s", cfg.Db.Backend)
 
-	err = pg.Pool.Ping(context.Background())
+	// Seed новой игры сохраняется в базе, дальше игра всегда идет с ним
+	seed, err := repos.Games.GetOrCreateSeed(context.Background(), random.New(cfg.Game.Seed).Seed())
 	if err != nil {
-		log.Fatal("APP - START - POSTGRES INI PROBLEM: %v", err)
-		return
+		l.Fatal("APP - START - GAME INI PROBLEM: %v", err)
+	}
+	l.Info("game seed: %d", seed)
+	// Поток порчи груза не должен повторять поток регистраций
+	damageSeed := cfg.Damage.Seed
+	if damageSeed == 0 {
+		damageSeed = seed + 1
 	}
 
-	userRepositoryInstance := userRepository.NewUserRepository(pg)
-	customerRepositoryInstance := customerRepository.NewCustomerRepository(pg)
-	loaderRepositoryInstance := loaderRepository.NewLoaderRepository(pg)
-	taskRepositoryInstance := taskRepository.NewTaskRepository(pg)
+	rules, err := rulesService.NewRegistry(cfg.Game, cfg.CargoTypes)
+	if err != nil {
+		l.Fatal("APP - START - RULES INI PROBLEM: %v", err)
+	}
+	pricer, err := pricingService.NewPricer(cfg.Pricing)
+	if err != nil {
+		l.Fatal("APP - START - PRICING INI PROBLEM: %v", err)
+	}
 
-	userServiceInstance := userService.NewUserService(pg, userRepositoryInstance, customerRepositoryInstance, loaderRepositoryInstance, taskRepositoryInstance)
-	taskServiceInstance := taskService.NewTaskService(pg, taskRepositoryInstance, loaderRepositoryInstance, customerRepositoryInstance)
-	jwtServiceInstance := jwtService.NewJWTService(cfg.SecretJWT)
+	// Ускоренные часы отсчитываются от создания игры, чтобы перезапуск не возвращал время назад
+	epoch, err := repos.Games.GetEpoch(context.Background())
+	if err != nil {
+		l.Fatal("APP - START - GAME INI PROBLEM: %v", err)
+	}
+	gameClock := clock.New(epoch, cfg.App.TimeFactor)
+	userServiceInstance := userService.NewUserService(repos.Tx, repos.Users, repos.Customers, repos.Loaders, repos.Tasks, repos.Games, cfg.CargoTypes, cfg.Progression, cfg.Game, rules, seed, gameClock, bcrypt.DefaultCost)
+	taskServiceInstance := taskService.NewTaskService(repos.Tx, repos.Tasks, repos.Loaders, repos.Customers, repos.Contracts, repos.Applications, rules, cfg.Damage, cfg.Progression, damageSeed, pricer, gameClock)
+	contractServiceInstance := contractService.NewContractService(repos.Tx, repos.Contracts, repos.Customers)
+	reviewServiceInstance := reviewService.NewReviewService(repos.Tx, repos.Reviews)
+	// Срок жизни токена считается по реальному времени, даже если игровое ускорено
+	jwtServiceInstance := jwtService.NewJWTService(cfg.SecretJWT, clock.Real{})
 
 	r := mux.NewRouter()
+	r.Use(middleware.Recover)
 	middlewareInstance := middleware.NewJWTMiddleware(jwtServiceInstance)
 
 	authControllerInstance := authController.NewAuthController(userServiceInstance, jwtServiceInstance)
@@ -54,28 +86,59 @@ func Run(cfg *config.Config) {
 	userControllerInstance := httpController.NewUsersController(userServiceInstance, taskServiceInstance, middlewareInstance)
 	userControllerInstance.RegisterRoutes(r)
 
-	httpServer := httpserver.New(r,
+	contractControllerInstance := contractController.NewContractController(contractServiceInstance, middlewareInstance)
+	contractControllerInstance.RegisterRoutes(r)
+
+	boardControllerInstance := boardController.NewBoardController(taskServiceInstance, middlewareInstance)
+	boardControllerInstance.RegisterRoutes(r)
+
+	reviewControllerInstance := reviewController.NewReviewController(reviewServiceInstance, middlewareInstance)
+	reviewControllerInstance.RegisterRoutes(r)
+
+	httpServer := httpserver.New(middleware.AccessLog(l, r),
 		httpserver.Port(cfg.HttpServer.Addr),
 		httpserver.ReadTimeout(cfg.HttpServer.ReadTimeout),
 		httpserver.WriteTimeout(cfg.HttpServer.WriteTimeout),
 		httpserver.ShutdownTimeout(cfg.HttpServer.ShutdownTimeout),
 	)
-	log.Println("Starting HTTP server on port 8080")
+	l.Info("starting HTTP server on %s", httpServer.Addr())
+
+	// Служебный сервер отдает метрики отдельно от API, чтобы /metrics не торчал наружу
+	var adminServer *httpserver.Server
+	var adminNotify <-chan error
+	if cfg.HttpServer.AdminAddr != "" {
+		adminMux := http.NewServeMux()
+		adminMux.Handle("/metrics", promhttp.Handler())
+		adminServer = httpserver.New(adminMux,
+			httpserver.Port(cfg.HttpServer.AdminAddr),
+			httpserver.ShutdownTimeout(cfg.HttpServer.ShutdownTimeout),
+		)
+		adminNotify = adminServer.Notify()
+		l.Info("starting admin HTTP server on %s", adminServer.Addr())
+	}
 
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 
-	log.Println("RUNNING APP:%v VERSION:%v", cfg.App.Name, cfg.App.Version)
+	l.Info("RUNNING APP:%v VERSION:%v", cfg.App.Name, cfg.App.Version)
 
 	select {
 	case s := <-interrupt:
-		log.Println("APP - RUN - signal: " + s.String())
+		l.Info("APP - RUN - signal: " + s.String())
 	case err = <-httpServer.Notify():
-		log.Fatal(fmt.Errorf("APP - RUN - HTTPSERVER.NOTIFY: %v", err))
+		l.Fatal(fmt.Errorf("APP - RUN - HTTPSERVER.NOTIFY: %w", err))
+	case err = <-adminNotify:
+		l.Fatal(fmt.Errorf("APP - RUN - ADMIN HTTPSERVER.NOTIFY: %w", err))
 	}
 
 	err = httpServer.Shutdown()
 	if err != nil {
-		log.Fatal(fmt.Errorf("APP - RUN - HTPPSERVER.SHUTDOWN: %v", err))
+		l.Fatal(fmt.Errorf("APP - RUN - HTPPSERVER.SHUTDOWN: %w", err))
+	}
+	if adminServer != nil {
+		err = adminServer.Shutdown()
+		if err != nil {
+			l.Fatal(fmt.Errorf("APP - RUN - ADMIN HTTPSERVER.SHUTDOWN: %w", err))
+		}
 	}
 }
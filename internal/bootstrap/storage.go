@@ -0,0 +1,53 @@
+package bootstrap
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/metrics"
+	"github.com/AhegaoHD/WBT/internal/repository"
+	"github.com/AhegaoHD/WBT/internal/repository/applicationRepository"
+	"github.com/AhegaoHD/WBT/internal/repository/contractRepository"
+	"github.com/AhegaoHD/WBT/internal/repository/customerRepository"
+	"github.com/AhegaoHD/WBT/internal/repository/gameRepository"
+	"github.com/AhegaoHD/WBT/internal/repository/loaderRepository"
+	"github.com/AhegaoHD/WBT/internal/repository/memoryRepository"
+	"github.com/AhegaoHD/WBT/internal/repository/reviewRepository"
+	"github.com/AhegaoHD/WBT/internal/repository/taskRepository"
+	"github.com/AhegaoHD/WBT/internal/repository/userRepository"
+	"github.com/AhegaoHD/WBT/pkg/postgres"
+	"github.com/AhegaoHD/WBT/pkg/retry"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newStorage открывает хранилище, выбранное в cfg.Db.Backend. close освобождает его ресурсы.
+func newStorage(cfg *config.Config) (repos *repository.Repositories, close func(), err error) {
+	if cfg.Db.Backend == config.BackendMemory {
+		store := memoryRepository.NewStore()
+		store.SetTxRetry(retry.Policy{Retries: cfg.Db.TxRetries, Delay: cfg.Db.TxRetryDelay})
+		return memoryRepository.NewRepositories(store), func() {}, nil
+	}
+
+	pg, err := postgres.New(postgres.GetConnString(&cfg.Db), postgres.MaxPoolSize(cfg.Db.MaxPoolSize),
+		postgres.TxRetry(cfg.Db.TxRetries, cfg.Db.TxRetryDelay))
+	if err != nil {
+		return nil, nil, err
+	}
+	err = pg.Pool.Ping(context.Background())
+	if err != nil {
+		pg.Close()
+		return nil, nil, err
+	}
+	prometheus.MustRegister(metrics.NewPoolCollector(pg.Pool))
+
+	return &repository.Repositories{
+		Tx:           pg,
+		Users:        userRepository.NewUserRepository(pg),
+		Customers:    customerRepository.NewCustomerRepository(pg),
+		Loaders:      loaderRepository.NewLoaderRepository(pg),
+		Tasks:        taskRepository.NewTaskRepository(pg),
+		Contracts:    contractRepository.NewContractRepository(pg),
+		Applications: applicationRepository.NewApplicationRepository(pg),
+		Reviews:      reviewRepository.NewReviewRepository(pg),
+		Games:        gameRepository.NewGameRepository(pg),
+	}, pg.Close, nil
+}
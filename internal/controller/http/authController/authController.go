@@ -3,6 +3,7 @@ package authController
 import (
 	"context"
 	"encoding/json"
+	"github.com/AhegaoHD/WBT/internal/controller/http/httperror"
 	"github.com/AhegaoHD/WBT/internal/models"
 	"github.com/gorilla/mux"
 	"net/http"
@@ -40,24 +41,29 @@ func (c *AuthController) RegisterHandler(w http.ResponseWriter, r *http.Request)
 	// Декодирование запроса
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if user == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
 		return
 	}
 	err = validateRegister(user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		httperror.Write(w, r, http.StatusUnauthorized, err)
 		return
 	}
 
 	user, err = c.userService.CreateUser(r.Context(), user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		httperror.WriteTx(w, r, http.StatusUnauthorized, err)
 		return
 	}
 
 	// Генерация JWT токена
 	token, err := c.jwtService.GenerateToken(user)
 	if err != nil {
+		httperror.Log(r, http.StatusInternalServerError, err)
 		http.Error(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
@@ -73,19 +79,24 @@ func (c *AuthController) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	// Декодирование запроса
 	err := json.NewDecoder(r.Body).Decode(&credentials)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if credentials == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
 		return
 	}
 
 	user, err := c.userService.AuthenticateUser(r.Context(), credentials)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		httperror.Write(w, r, http.StatusUnauthorized, err)
 		return
 	}
 
 	// Генерация JWT токена
 	token, err := c.jwtService.GenerateToken(user)
 	if err != nil {
+		httperror.Log(r, http.StatusInternalServerError, err)
 		http.Error(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,198 @@
+package boardController
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/AhegaoHD/WBT/internal/controller/http/etag"
+	"github.com/AhegaoHD/WBT/internal/controller/http/httperror"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type BoardController struct {
+	taskService taskService
+	middleware  middleware
+}
+
+type taskService interface {
+	PublishTask(ctx context.Context, req *models.PublishTaskRequest) error
+	GetBoard(ctx context.Context) ([]models.Task, error)
+	Apply(ctx context.Context, req *models.ApplyRequest) (*models.Application, error)
+	GetApplications(ctx context.Context, user *models.User, taskID uuid.UUID) ([]models.Application, error)
+	ConfirmCrew(ctx context.Context, req *models.ConfirmCrewRequest) error
+}
+
+type middleware interface {
+	Middleware(next http.Handler) http.Handler
+}
+
+func NewBoardController(taskService taskService, middleware middleware) *BoardController {
+	return &BoardController{taskService: taskService, middleware: middleware}
+}
+
+func (c *BoardController) RegisterRoutes(r *mux.Router) {
+	api := r.PathPrefix("").Subrouter()
+	api.Use(c.middleware.Middleware)
+	api.HandleFunc("/board", c.GetBoard).Methods("GET")
+	api.HandleFunc("/board/publish", c.PublishTask).Methods("POST")
+	api.HandleFunc("/board/apply", c.Apply).Methods("POST")
+	api.HandleFunc("/board/applications", c.GetApplications).Methods("GET")
+	api.HandleFunc("/board/confirm", c.ConfirmCrew).Methods("POST")
+}
+
+func (c *BoardController) GetBoard(w http.ResponseWriter, r *http.Request) {
+	_, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tasks, err := c.taskService.GetBoard(r.Context())
+	if err != nil {
+		httperror.Write(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, tasks)
+}
+
+func (c *BoardController) PublishTask(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req *models.PublishTaskRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if req == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
+		return
+	}
+	req.User = user
+	req.Version, err = etag.IfMatch(r)
+	if err != nil {
+		httperror.Write(w, r, http.StatusPreconditionFailed, err)
+		return
+	}
+
+	err = c.taskService.PublishTask(r.Context(), req)
+	if err != nil {
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *BoardController) Apply(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req *models.ApplyRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if req == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
+		return
+	}
+	req.User = user
+
+	err = validateApply(req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	application, err := c.taskService.Apply(r.Context(), req)
+	if err != nil {
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusCreated, application)
+}
+
+func (c *BoardController) GetApplications(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var taskID uuid.UUID
+	if raw := r.URL.Query().Get("task_id"); raw != "" {
+		var err error
+		taskID, err = uuid.Parse(raw)
+		if err != nil {
+			httperror.Write(w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	applications, err := c.taskService.GetApplications(r.Context(), user, taskID)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, applications)
+}
+
+func (c *BoardController) ConfirmCrew(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req *models.ConfirmCrewRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if req == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
+		return
+	}
+	req.User = user
+	req.Version, err = etag.IfMatch(r)
+	if err != nil {
+		httperror.Write(w, r, http.StatusPreconditionFailed, err)
+		return
+	}
+
+	err = validateConfirmCrew(req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	err = c.taskService.ConfirmCrew(r.Context(), req)
+	if err != nil {
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *BoardController) writeJSONResponse(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.FromContext(r.Context()).Error("failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package boardController
+
+import (
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+)
+
+func validateApply(req *models.ApplyRequest) error {
+	if req.AskingPrice <= 0 {
+		return errors.New("req.AskingPrice <= 0")
+	}
+	return nil
+}
+
+func validateConfirmCrew(req *models.ConfirmCrewRequest) error {
+	if len(req.ApplicationIDs) == 0 {
+		return errors.New("len(req.ApplicationIDs)==0")
+	}
+	return nil
+}
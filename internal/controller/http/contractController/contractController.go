@@ -0,0 +1,150 @@
+package contractController
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/AhegaoHD/WBT/internal/controller/http/httperror"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type ContractController struct {
+	contractService contractService
+	middleware      middleware
+}
+
+type contractService interface {
+	OfferContract(ctx context.Context, req *models.OfferContractRequest) (*models.Contract, error)
+	AcceptContract(ctx context.Context, user *models.User, contractID uuid.UUID) error
+	DeclineContract(ctx context.Context, user *models.User, contractID uuid.UUID) error
+	TerminateContract(ctx context.Context, user *models.User, contractID uuid.UUID) error
+	GetContracts(ctx context.Context, user *models.User) ([]models.Contract, error)
+	GetRoster(ctx context.Context, user *models.User) ([]models.RosterEntry, error)
+}
+
+type middleware interface {
+	Middleware(next http.Handler) http.Handler
+}
+
+func NewContractController(contractService contractService, middleware middleware) *ContractController {
+	return &ContractController{contractService: contractService, middleware: middleware}
+}
+
+func (c *ContractController) RegisterRoutes(r *mux.Router) {
+	api := r.PathPrefix("").Subrouter()
+	api.Use(c.middleware.Middleware)
+	api.HandleFunc("/contracts", c.GetContracts).Methods("GET")
+	api.HandleFunc("/contracts", c.OfferContract).Methods("POST")
+	api.HandleFunc("/contracts/{id}/accept", c.AcceptContract).Methods("POST")
+	api.HandleFunc("/contracts/{id}/decline", c.DeclineContract).Methods("POST")
+	api.HandleFunc("/contracts/{id}/terminate", c.TerminateContract).Methods("POST")
+	api.HandleFunc("/roster", c.GetRoster).Methods("GET")
+}
+
+func (c *ContractController) OfferContract(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req *models.OfferContractRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if req == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
+		return
+	}
+	req.User = user
+
+	err = validateOfferContract(req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	contract, err := c.contractService.OfferContract(r.Context(), req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusCreated, contract)
+}
+
+func (c *ContractController) AcceptContract(w http.ResponseWriter, r *http.Request) {
+	c.handleContractAction(w, r, c.contractService.AcceptContract)
+}
+
+func (c *ContractController) DeclineContract(w http.ResponseWriter, r *http.Request) {
+	c.handleContractAction(w, r, c.contractService.DeclineContract)
+}
+
+func (c *ContractController) TerminateContract(w http.ResponseWriter, r *http.Request) {
+	c.handleContractAction(w, r, c.contractService.TerminateContract)
+}
+
+func (c *ContractController) GetContracts(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	contracts, err := c.contractService.GetContracts(r.Context(), user)
+	if err != nil {
+		httperror.Write(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, contracts)
+}
+
+func (c *ContractController) GetRoster(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	roster, err := c.contractService.GetRoster(r.Context(), user)
+	if err != nil {
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, roster)
+}
+
+func (c *ContractController) handleContractAction(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, user *models.User, contractID uuid.UUID) error) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	contractID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	err = action(r.Context(), user, contractID)
+	if err != nil {
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *ContractController) writeJSONResponse(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.FromContext(r.Context()).Error("failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package contractController
+
+import (
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/google/uuid"
+)
+
+func validateOfferContract(req *models.OfferContractRequest) error {
+	if req.LoaderID == uuid.Nil {
+		return errors.New("req.LoaderID is empty")
+	}
+	if req.Kind != models.ContractPerTask && req.Kind != models.ContractRetainer {
+		return errors.New("req.Kind!=\"per_task\" && req.Kind!=\"retainer\"")
+	}
+	if req.Rate < 0 {
+		return errors.New("req.Rate < 0")
+	}
+	if req.Kind == models.ContractRetainer && req.Rate == 0 {
+		return errors.New("retainer contract needs req.Rate > 0")
+	}
+	return nil
+}
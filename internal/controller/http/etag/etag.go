@@ -0,0 +1,34 @@
+package etag
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrPreconditionFailed - заголовок If-Match не содержит версию ресурса.
+var ErrPreconditionFailed = errors.New("If-Match must be \"*\" or an ETag returned by the server")
+
+// Set отдает версию ресурса в заголовке ETag.
+func Set(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// IfMatch возвращает версию ресурса, которую клиент ожидает изменить. nil - заголовка
+// нет или он равен "*", тогда версия не проверяется.
+func IfMatch(r *http.Request) (*int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return nil, nil
+	}
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return nil, ErrPreconditionFailed
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil {
+		return nil, ErrPreconditionFailed
+	}
+	return &version, nil
+}
@@ -0,0 +1,65 @@
+package etag_test
+
+import (
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/controller/http/etag"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    *int
+		wantErr error
+	}{
+		{"no header", "", nil, nil},
+		{"any version", "*", nil, nil},
+		{"quoted version", `"3"`, intPtr(3), nil},
+		{"surrounding spaces", `  "12" `, intPtr(12), nil},
+		// If-Match сравнивает ETag строго, слабый ETag не совпадает ни с одной версией
+		{"weak version", `W/"3"`, nil, etag.ErrPreconditionFailed},
+		{"unquoted version", "3", nil, etag.ErrPreconditionFailed},
+		{"unterminated quote", `"3`, nil, etag.ErrPreconditionFailed},
+		{"not a number", `"abc"`, nil, etag.ErrPreconditionFailed},
+		{"list of versions", `"1", "2"`, nil, etag.ErrPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/tasks/1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			got, err := etag.IfMatch(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("version = %d, want nil", *got)
+			case tt.want != nil && (got == nil || *got != *tt.want):
+				t.Errorf("version = %v, want %d", got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRoundTrip(t *testing.T) {
+	w := httptest.NewRecorder()
+	etag.Set(w, 7)
+
+	r := httptest.NewRequest("PUT", "/tasks/1", nil)
+	r.Header.Set("If-Match", w.Header().Get("ETag"))
+	got, err := etag.IfMatch(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || *got != 7 {
+		t.Errorf("version = %v, want 7", got)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
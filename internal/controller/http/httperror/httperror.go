@@ -0,0 +1,45 @@
+// Package httperror отвечает клиенту ошибкой и пишет ее в лог запроса. Обработчики
+// отдают через Write каждую ошибку, полученную от разбора запроса или сервиса, поэтому
+// у каждого ответа с ошибкой есть запись с ее текстом и request_id.
+package httperror
+
+import (
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/AhegaoHD/WBT/pkg/retry"
+	"net/http"
+)
+
+// Write отвечает текстом err с кодом status и пишет err в лог.
+func Write(w http.ResponseWriter, r *http.Request, status int, err error) {
+	Log(r, status, err)
+	http.Error(w, err.Error(), status)
+}
+
+// WriteTx отвечает на ошибку сервиса, работающего в транзакции: устаревшая версия из If-Match -
+// 412, транзакция, которая так и не прошла из-за конфликтов с параллельными запросами, - 409
+// (запрос можно повторить), остальные ошибки - status.
+func WriteTx(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if errors.Is(err, models.ErrVersionMismatch) {
+		Write(w, r, http.StatusPreconditionFailed, err)
+		return
+	}
+	if retry.Retryable(err) {
+		Log(r, http.StatusConflict, err)
+		http.Error(w, "transaction conflict, try again", http.StatusConflict)
+		return
+	}
+	Write(w, r, status, err)
+}
+
+// Log пишет в лог ошибку, на которую обработчик отвечает кодом status: 5xx - как Error,
+// остальные коды - как Warn. Нужен, когда клиенту уходит не текст err.
+func Log(r *http.Request, status int, err error) {
+	l := logger.FromContext(r.Context()).With("status", status)
+	if status >= http.StatusInternalServerError {
+		l.Error(err)
+		return
+	}
+	l.Warn(err.Error())
+}
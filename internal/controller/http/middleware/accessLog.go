@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/internal/metrics"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/AhegaoHD/WBT/pkg/requestid"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute - шаблон пути в логе для запросов, не попавших ни в один маршрут.
+const unmatchedRoute = "<unmatched>"
+
+// requestInfo собирает сведения о запросе, которые становятся известны во вложенных
+// обработчиках: контекст вниз по цепочке передается копиями, поэтому AccessLog держит указатель.
+type requestInfo struct {
+	userID uuid.UUID
+	route  string
+}
+
+type requestInfoKey struct{}
+
+// setUserID запоминает пользователя запроса для записи в журнал доступа.
+func setUserID(ctx context.Context, userID uuid.UUID) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.userID = userID
+	}
+}
+
+// recordRoute запоминает шаблон маршрута, который нашел роутер, для журнала доступа и метрик.
+func recordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					info.route = template
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// responseRecorder запоминает код ответа и число отправленных байт.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// AccessLog оборачивает router: принимает X-Request-ID клиента или создает новый, возвращает
+// его в ответе, кладет в контекст идентификатор и логгер с полем request_id и после ответа
+// пишет запись о запросе с шаблоном маршрута, кодом ответа, размером, временем и пользователем.
+// Те же метод, шаблон маршрута и код ответа становятся метками метрик HTTP. Шаблон маршрута
+// запоминает middleware, которую AccessLog добавляет в router, поэтому запрос сопоставляется один раз.
+// Запрос, прерванный паникой, записывается с полем aborted.
+func AccessLog(l *logger.Logger, router *mux.Router) http.Handler {
+	router.Use(recordRoute)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+
+		info := &requestInfo{route: unmatchedRoute}
+		reqLogger := l.With(logger.FieldRequestID, id)
+		ctx := requestid.WithContext(r.Context(), id)
+		ctx = logger.WithContext(ctx, reqLogger)
+		ctx = context.WithValue(ctx, requestInfoKey{}, info)
+		r = r.WithContext(ctx)
+
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			// Прерванный ответ тоже попадает в журнал, после чего паника уходит дальше в net/http
+			p := recover()
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+				if p != nil {
+					rec.status = http.StatusInternalServerError
+				}
+			}
+
+			route := info.route
+			latency := time.Since(start)
+			metrics.HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(latency.Seconds())
+
+			entry := reqLogger.With(
+				"method", r.Method,
+				"path", route,
+				"status", rec.status,
+				"bytes", rec.bytes,
+				"latency_ms", latency,
+			)
+			if info.userID != uuid.Nil {
+				entry = entry.With(logger.FieldUserID, info.userID)
+			}
+			switch {
+			case p != nil:
+				entry.With("aborted", true).Error("http request")
+				panic(p)
+			case rec.status >= http.StatusInternalServerError:
+				entry.Error("http request")
+			default:
+				entry.Info("http request")
+			}
+		}()
+		router.ServeHTTP(rec, r)
+	})
+}
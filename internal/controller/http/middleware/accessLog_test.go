@@ -0,0 +1,177 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/AhegaoHD/WBT/internal/controller/http/middleware"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/AhegaoHD/WBT/pkg/requestid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newServer собирает цепочку как в bootstrap: Recover на роутере, AccessLog снаружи.
+func newServer(buf *bytes.Buffer, handler http.HandlerFunc) http.Handler {
+	r := mux.NewRouter()
+	r.Use(middleware.Recover)
+	r.HandleFunc("/tasks/{id}", handler)
+	return middleware.AccessLog(logger.NewWithWriter(buf, "info", logger.FormatJSON), r)
+}
+
+// accessEntry возвращает единственную запись журнала доступа.
+func accessEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var found []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry["message"] == "http request" {
+			found = append(found, entry)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("%d access log entries, want 1", len(found))
+	}
+	return found[0]
+}
+
+func TestAccessLogRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		echo   bool
+	}{
+		{"client id", "req-42", true},
+		{"no id", "", false},
+		{"id with spaces", "req 42", false},
+		{"id too long", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var seen string
+			h := newServer(&buf, func(w http.ResponseWriter, r *http.Request) {
+				seen = requestid.FromContext(r.Context())
+			})
+
+			r := httptest.NewRequest("GET", "/tasks/1", nil)
+			if tt.header != "" {
+				r.Header.Set(requestid.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			id := w.Header().Get(requestid.Header)
+			if tt.echo && id != tt.header {
+				t.Errorf("%s = %q, want %q", requestid.Header, id, tt.header)
+			}
+			if !tt.echo && (id == tt.header || !requestid.Valid(id)) {
+				t.Errorf("%s = %q, want a new id", requestid.Header, id)
+			}
+			if seen != id {
+				t.Errorf("request id in context = %q, want %q", seen, id)
+			}
+			entry := accessEntry(t, &buf)
+			if entry[logger.FieldRequestID] != id {
+				t.Errorf("logged request_id = %v, want %q", entry[logger.FieldRequestID], id)
+			}
+		})
+	}
+}
+
+func TestAccessLogEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		handler http.HandlerFunc
+		route   string
+		status  float64
+		level   string
+		aborted bool
+	}{
+		{
+			name:    "ok",
+			path:    "/tasks/1",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) },
+			route:   "/tasks/{id}",
+			status:  http.StatusOK,
+			level:   "info",
+		},
+		{
+			name:    "unmatched",
+			path:    "/unknown",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			route:   "<unmatched>",
+			status:  http.StatusNotFound,
+			level:   "info",
+		},
+		{
+			name:    "recovered panic",
+			path:    "/tasks/1",
+			handler: func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			route:   "/tasks/{id}",
+			status:  http.StatusInternalServerError,
+			level:   "error",
+		},
+		{
+			name:    "aborted handler",
+			path:    "/tasks/1",
+			handler: func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) },
+			route:   "/tasks/{id}",
+			status:  http.StatusInternalServerError,
+			level:   "error",
+			aborted: true,
+		},
+		{
+			name: "panic after the response started",
+			path: "/tasks/1",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				panic("boom")
+			},
+			route:   "/tasks/{id}",
+			status:  http.StatusOK,
+			level:   "error",
+			aborted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := newServer(&buf, tt.handler)
+
+			func() {
+				defer func() {
+					p := recover()
+					if tt.aborted && p != http.ErrAbortHandler {
+						t.Errorf("panic = %v, want %v", p, http.ErrAbortHandler)
+					}
+					if !tt.aborted && p != nil {
+						t.Errorf("unexpected panic %v", p)
+					}
+				}()
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+			}()
+
+			entry := accessEntry(t, &buf)
+			if entry["path"] != tt.route {
+				t.Errorf("path = %v, want %q", entry["path"], tt.route)
+			}
+			if entry["status"] != tt.status {
+				t.Errorf("status = %v, want %v", entry["status"], tt.status)
+			}
+			if entry["level"] != tt.level {
+				t.Errorf("level = %v, want %q", entry["level"], tt.level)
+			}
+			if aborted, _ := entry["aborted"].(bool); aborted != tt.aborted {
+				t.Errorf("aborted = %v, want %v", aborted, tt.aborted)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/logger"
 	"github.com/dgrijalva/jwt-go"
 	"net/http"
 )
@@ -44,6 +45,8 @@ func (m *JWTMiddleware) Middleware(next http.Handler) http.Handler {
 		}
 		user := claims.User
 		ctx := context.WithValue(r.Context(), "user", user)
+		ctx = logger.With(ctx, logger.FieldUserID, user.UserID)
+		setUserID(ctx, user.UserID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/AhegaoHD/WBT/internal/metrics"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/AhegaoHD/WBT/pkg/requestid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"runtime/debug"
+)
+
+// Problem - тело ответа об ошибке в формате RFC 7807 (application/problem+json).
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Recover перехватывает панику в обработчике: пишет в лог значение паники и стек вместе
+// с request_id из контекста, увеличивает metrics.HTTPPanics и отвечает 500 с телом Problem.
+// Если обработчик уже начал ответ, изменить код нельзя, и соединение просто закрывается.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// Паника, которой net/http сам прерывает ответ, не ошибка обработчика
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			route := unmatchedRoute
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+			metrics.HTTPPanics.WithLabelValues(route).Inc()
+			logger.FromContext(r.Context()).With("path", route, "panic", p, "stack", string(debug.Stack())).Error("panic in http handler")
+
+			if rec.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			writeProblem(rec, r, http.StatusInternalServerError, "internal error")
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// writeProblem отвечает телом Problem с кодом status.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: requestid.FromContext(r.Context()),
+	})
+	if err != nil {
+		logger.FromContext(r.Context()).Error("failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/AhegaoHD/WBT/internal/controller/http/middleware"
+	"github.com/AhegaoHD/WBT/internal/metrics"
+	"github.com/AhegaoHD/WBT/pkg/requestid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		problem bool
+		panics  float64
+	}{
+		{
+			name:    "no panic",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) },
+			status:  http.StatusNoContent,
+		},
+		{
+			name:    "panic with a value",
+			handler: func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			status:  http.StatusInternalServerError,
+			problem: true,
+			panics:  1,
+		},
+		{
+			name:    "panic with an error",
+			handler: func(w http.ResponseWriter, r *http.Request) { panic(http.ErrBodyNotAllowed) },
+			status:  http.StatusInternalServerError,
+			problem: true,
+			panics:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := newServer(&buf, tt.handler)
+			panics := metrics.HTTPPanics.WithLabelValues("/tasks/{id}")
+			before := testutil.ToFloat64(panics)
+
+			r := httptest.NewRequest("GET", "/tasks/1", nil)
+			r.Header.Set(requestid.Header, "req-42")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := testutil.ToFloat64(panics) - before; got != tt.panics {
+				t.Errorf("panic counter grew by %v, want %v", got, tt.panics)
+			}
+			if !tt.problem {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want application/problem+json", ct)
+			}
+			var problem middleware.Problem
+			if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			want := middleware.Problem{
+				Type:      "about:blank",
+				Title:     http.StatusText(http.StatusInternalServerError),
+				Status:    http.StatusInternalServerError,
+				Detail:    "internal error",
+				Instance:  "/tasks/1",
+				RequestID: "req-42",
+			}
+			if problem != want {
+				t.Errorf("problem = %+v, want %+v", problem, want)
+			}
+		})
+	}
+}
+
+func TestRecoverSkipsAbortHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := newServer(&buf, func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) })
+	panics := metrics.HTTPPanics.WithLabelValues("/tasks/{id}")
+	before := testutil.ToFloat64(panics)
+
+	func() {
+		defer func() {
+			if p := recover(); p != http.ErrAbortHandler {
+				t.Errorf("panic = %v, want %v", p, http.ErrAbortHandler)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/tasks/1", nil))
+	}()
+
+	if got := testutil.ToFloat64(panics) - before; got != 0 {
+		t.Errorf("panic counter grew by %v, want 0", got)
+	}
+}
@@ -0,0 +1,93 @@
+package reviewController
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/AhegaoHD/WBT/internal/controller/http/httperror"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type ReviewController struct {
+	reviewService reviewService
+	middleware    middleware
+}
+
+type reviewService interface {
+	CreateReview(ctx context.Context, req *models.CreateReviewRequest) (*models.Review, error)
+	GetReviews(ctx context.Context, user *models.User) ([]models.Review, error)
+}
+
+type middleware interface {
+	Middleware(next http.Handler) http.Handler
+}
+
+func NewReviewController(reviewService reviewService, middleware middleware) *ReviewController {
+	return &ReviewController{reviewService: reviewService, middleware: middleware}
+}
+
+func (c *ReviewController) RegisterRoutes(r *mux.Router) {
+	api := r.PathPrefix("").Subrouter()
+	api.Use(c.middleware.Middleware)
+	api.HandleFunc("/reviews", c.GetReviews).Methods("GET")
+	api.HandleFunc("/reviews", c.CreateReview).Methods("POST")
+}
+
+func (c *ReviewController) CreateReview(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req *models.CreateReviewRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if req == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
+		return
+	}
+	req.User = user
+
+	err = validateCreateReview(req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	review, err := c.reviewService.CreateReview(r.Context(), req)
+	if err != nil {
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusCreated, review)
+}
+
+func (c *ReviewController) GetReviews(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reviews, err := c.reviewService.GetReviews(r.Context(), user)
+	if err != nil {
+		httperror.Write(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, reviews)
+}
+
+func (c *ReviewController) writeJSONResponse(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.FromContext(r.Context()).Error("failed to encode response: %v", err)
+	}
+}
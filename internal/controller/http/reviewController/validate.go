@@ -0,0 +1,18 @@
+package reviewController
+
+import (
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+)
+
+const maxCommentLength = 1000
+
+func validateCreateReview(req *models.CreateReviewRequest) error {
+	if req.Rating < 1 || req.Rating > 5 {
+		return errors.New("req.Rating must be in 1..5")
+	}
+	if len([]rune(req.Comment)) > maxCommentLength {
+		return errors.New("comment is too long")
+	}
+	return nil
+}
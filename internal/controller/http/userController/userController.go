@@ -3,9 +3,12 @@ package userController
 import (
 	"context"
 	"encoding/json"
+	"github.com/AhegaoHD/WBT/internal/controller/http/etag"
+	"github.com/AhegaoHD/WBT/internal/controller/http/httperror"
 	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"log"
 	"net/http"
 )
 
@@ -18,10 +21,21 @@ type UsersController struct {
 type userService interface {
 	GetUserDetails(ctx context.Context, user *models.User) (interface{}, error)
 	GetUserTasks(ctx context.Context, user *models.User) (interface{}, error)
+	FindLoaders(ctx context.Context, user *models.User, filter *models.LoaderFilter) ([]models.Loader, error)
+	GetLoader(ctx context.Context, user *models.User, loaderID uuid.UUID) (*models.Loader, error)
 }
 
 type taskService interface {
 	StartTask(ctx context.Context, req *models.StartTaskRequest) error
+	StartTasks(ctx context.Context, req *models.BatchStartTaskRequest) (*models.BatchStartTaskResult, error)
+	GetTask(ctx context.Context, user *models.User, taskID uuid.UUID) (*models.Task, error)
+	SetTaskPriority(ctx context.Context, req *models.SetTaskPriorityRequest) error
+	GetTaskQueue(ctx context.Context, user *models.User) ([]models.Task, error)
+	AutoDispatch(ctx context.Context, user *models.User) (*models.DispatchResult, error)
+	GetQuotes(ctx context.Context, user *models.User) ([]models.Quote, error)
+	GetDamages(ctx context.Context, user *models.User) ([]models.DamageEvent, error)
+	PlanTasks(ctx context.Context, user *models.User) (*models.Plan, error)
+	ExecutePlan(ctx context.Context, user *models.User, plan *models.Plan) (*models.BatchStartTaskResult, error)
 }
 
 type middleware interface {
@@ -37,7 +51,18 @@ func (c *UsersController) RegisterRoutes(r *mux.Router) {
 	api.Use(c.middleware.Middleware)
 	api.HandleFunc("/me", c.GetUserDetails).Methods("GET")
 	api.HandleFunc("/tasks", c.GetUserTasks).Methods("GET")
+	api.HandleFunc("/loaders", c.FindLoaders).Methods("GET")
+	api.HandleFunc("/loaders/{id}", c.GetLoader).Methods("GET")
 	api.HandleFunc("/start", c.StartTask).Methods("POST")
+	api.HandleFunc("/start/batch", c.StartTasks).Methods("POST")
+	api.HandleFunc("/tasks/priority", c.SetTaskPriority).Methods("POST")
+	api.HandleFunc("/tasks/{id}", c.GetTask).Methods("GET")
+	api.HandleFunc("/queue", c.GetTaskQueue).Methods("GET")
+	api.HandleFunc("/dispatch", c.AutoDispatch).Methods("POST")
+	api.HandleFunc("/damages", c.GetDamages).Methods("GET")
+	api.HandleFunc("/quotes", c.GetQuotes).Methods("GET")
+	api.HandleFunc("/plan", c.PlanTasks).Methods("GET")
+	api.HandleFunc("/plan/execute", c.ExecutePlan).Methods("POST")
 }
 
 func (c *UsersController) GetUserDetails(w http.ResponseWriter, r *http.Request) {
@@ -49,10 +74,16 @@ func (c *UsersController) GetUserDetails(w http.ResponseWriter, r *http.Request)
 
 	userDetails, err := c.userService.GetUserDetails(r.Context(), user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httperror.Write(w, r, http.StatusInternalServerError, err)
 		return
 	}
-	c.writeJSONResponse(w, http.StatusOK, userDetails)
+	switch details := userDetails.(type) {
+	case *models.CustomerDetails:
+		etag.Set(w, details.Info.Version)
+	case *models.LoaderDetails:
+		etag.Set(w, details.Version)
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, userDetails)
 }
 
 func (c *UsersController) GetUserTasks(w http.ResponseWriter, r *http.Request) {
@@ -64,10 +95,77 @@ func (c *UsersController) GetUserTasks(w http.ResponseWriter, r *http.Request) {
 
 	userDetails, err := c.userService.GetUserTasks(r.Context(), user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httperror.Write(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, userDetails)
+}
+
+func (c *UsersController) FindLoaders(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := parseLoaderFilter(r.URL.Query())
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	loaders, err := c.userService.FindLoaders(r.Context(), user, filter)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, loaders)
+}
+
+// GetLoader отдает грузчика с версией в ETag.
+func (c *UsersController) GetLoader(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	loaderID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	loader, err := c.userService.GetLoader(r.Context(), user, loaderID)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	etag.Set(w, loader.Version)
+	c.writeJSONResponse(w, r, http.StatusOK, loader)
+}
+
+// GetTask отдает задачу с версией в ETag; ее передают в If-Match при изменении задачи.
+func (c *UsersController) GetTask(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	task, err := c.taskService.GetTask(r.Context(), user, taskID)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
 		return
 	}
-	c.writeJSONResponse(w, http.StatusOK, userDetails)
+	etag.Set(w, task.Version)
+	c.writeJSONResponse(w, r, http.StatusOK, task)
 }
 
 func (c *UsersController) StartTask(w http.ResponseWriter, r *http.Request) {
@@ -82,29 +180,227 @@ func (c *UsersController) StartTask(w http.ResponseWriter, r *http.Request) {
 	// Декодирование запроса
 	err := json.NewDecoder(r.Body).Decode(&startTask)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if startTask == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
 		return
 	}
 	startTask.User = user
+	startTask.Version, err = etag.IfMatch(r)
+	if err != nil {
+		httperror.Write(w, r, http.StatusPreconditionFailed, err)
+		return
+	}
 
 	err = validateStartTask(startTask)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httperror.Write(w, r, http.StatusBadRequest, err)
 		return
 	}
 
 	err = c.taskService.StartTask(r.Context(), startTask)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *UsersController) StartTasks(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req *models.BatchStartTaskRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if req == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
+		return
+	}
+	req.User = user
+
+	err = validateBatchStartTask(req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := c.taskService.StartTasks(r.Context(), req)
+	if err != nil {
+		if result != nil {
+			c.writeJSONResponse(w, r, http.StatusBadRequest, result)
+			return
+		}
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, result)
+}
+
+func (c *UsersController) SetTaskPriority(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req *models.SetTaskPriorityRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if req == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
+		return
+	}
+	req.User = user
+	req.Version, err = etag.IfMatch(r)
+	if err != nil {
+		httperror.Write(w, r, http.StatusPreconditionFailed, err)
+		return
+	}
+
+	err = validateSetTaskPriority(req)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	err = c.taskService.SetTaskPriority(r.Context(), req)
+	if err != nil {
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
-func (c *UsersController) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+func (c *UsersController) GetTaskQueue(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	queue, err := c.taskService.GetTaskQueue(r.Context(), user)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, queue)
+}
+
+func (c *UsersController) AutoDispatch(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := c.taskService.AutoDispatch(r.Context(), user)
+	if err != nil {
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, result)
+}
+
+func (c *UsersController) GetDamages(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	damages, err := c.taskService.GetDamages(r.Context(), user)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, damages)
+}
+
+func (c *UsersController) GetQuotes(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	quotes, err := c.taskService.GetQuotes(r.Context(), user)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, quotes)
+}
+
+func (c *UsersController) PlanTasks(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	plan, err := c.taskService.PlanTasks(r.Context(), user)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, plan)
+}
+
+func (c *UsersController) ExecutePlan(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var plan *models.Plan
+
+	err := json.NewDecoder(r.Body).Decode(&plan)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if plan == nil {
+		http.Error(w, "empty request", http.StatusBadRequest)
+		return
+	}
+
+	err = validatePlan(plan)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := c.taskService.ExecutePlan(r.Context(), user, plan)
+	if err != nil {
+		if result != nil {
+			c.writeJSONResponse(w, r, http.StatusBadRequest, result)
+			return
+		}
+		httperror.WriteTx(w, r, http.StatusBadRequest, err)
+		return
+	}
+	c.writeJSONResponse(w, r, http.StatusOK, result)
+}
+
+func (c *UsersController) writeJSONResponse(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Println("Failed to encode response:", err)
+		logger.FromContext(r.Context()).Error("failed to encode response: %v", err)
 	}
 }
@@ -2,7 +2,10 @@ package userController
 
 import (
 	"errors"
+	"fmt"
 	"github.com/AhegaoHD/WBT/internal/models"
+	"net/url"
+	"strconv"
 )
 
 func validateStartTask(startTask *models.StartTaskRequest) error {
@@ -11,3 +14,63 @@ func validateStartTask(startTask *models.StartTaskRequest) error {
 	}
 	return nil
 }
+
+const maxBatchSize = 100
+
+func validateBatchStartTask(req *models.BatchStartTaskRequest) error {
+	if req.Mode != models.BatchModeAllOrNothing && req.Mode != models.BatchModeBestEffort {
+		return errors.New("req.Mode != \"all_or_nothing\" && req.Mode != \"best_effort\"")
+	}
+	if len(req.Tasks) == 0 {
+		return errors.New("len(req.Tasks)==0")
+	}
+	if len(req.Tasks) > maxBatchSize {
+		return fmt.Errorf("len(req.Tasks) > %d", maxBatchSize)
+	}
+	for i := range req.Tasks {
+		err := validateStartTask(&req.Tasks[i])
+		if err != nil {
+			return fmt.Errorf("tasks[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validatePlan(plan *models.Plan) error {
+	if len(plan.Assignments) == 0 {
+		return errors.New("len(plan.Assignments)==0")
+	}
+	if len(plan.Assignments) > maxBatchSize {
+		return fmt.Errorf("len(plan.Assignments) > %d", maxBatchSize)
+	}
+	for i, assignment := range plan.Assignments {
+		if len(assignment.LoaderIDs) == 0 {
+			return fmt.Errorf("assignments[%d]: len(LoaderIDs)==0", i)
+		}
+	}
+	return nil
+}
+
+func validateSetTaskPriority(req *models.SetTaskPriorityRequest) error {
+	if req.Priority < 0 {
+		return errors.New("req.Priority < 0")
+	}
+	return nil
+}
+
+func parseLoaderFilter(query url.Values) (*models.LoaderFilter, error) {
+	filter := &models.LoaderFilter{Sort: query.Get("sort")}
+	switch filter.Sort {
+	case "", models.LoaderSortRating, models.LoaderSortSalary, models.LoaderSortMaxWeight:
+	default:
+		return nil, errors.New("sort must be one of rating, salary, max_weight")
+	}
+	if raw := query.Get("min_rating"); raw != "" {
+		minRating, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("min_rating: %w", err)
+		}
+		filter.MinRating = &minRating
+	}
+	return filter, nil
+}
@@ -0,0 +1,26 @@
+// Package lockorder задает порядок, в котором транзакции блокируют строки: по возрастанию
+// идентификатора, как Postgres при ORDER BY. Сервисы и хранилище в памяти берут его отсюда,
+// чтобы параллельные транзакции не блокировали одни и те же строки в разном порядке.
+package lockorder
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+	"sort"
+)
+
+// Sorted возвращает идентификаторы без повторов в порядке блокировки.
+func Sorted(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	sorted := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	return sorted
+}
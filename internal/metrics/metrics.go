@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "wbt"
+
+// HTTPPanics - паники в обработчиках HTTP, перехваченные middleware.Recover, по шаблону маршрута.
+var HTTPPanics = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "panics_total",
+	Help:      "Panics recovered in HTTP handlers.",
+}, []string{"path"})
+
+// HTTPRequests - обработанные HTTP-запросы по методу, шаблону маршрута и коду ответа.
+var HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "requests_total",
+	Help:      "HTTP requests by method, route template and status.",
+}, []string{"method", "path", "status"})
+
+// HTTPRequestDuration - время обработки HTTP-запросов по методу и шаблону маршрута.
+var HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "request_duration_seconds",
+	Help:      "HTTP request latency by method and route template.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method", "path"})
+
+// UsersRegistered - зарегистрированные пользователи по типу: customer или loader.
+var UsersRegistered = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "users",
+	Name:      "registered_total",
+	Help:      "Registered users by type.",
+}, []string{"user_type"})
+
+// TasksStarted - зафиксированные рейсы StartTask, включая задачи пакетов, и ConfirmCrew.
+var TasksStarted = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "tasks",
+	Name:      "started_total",
+	Help:      "Committed StartTask and ConfirmCrew trips, batch items included.",
+})
+
+// TasksFailed - отклоненные StartTask и ConfirmCrew по причине.
+var TasksFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "tasks",
+	Name:      "failed_total",
+	Help:      "Rejected StartTask and ConfirmCrew calls by reason.",
+}, []string{"reason"})
+
+// CapitalSpent - капитал заказчиков, выплаченный бригадам в зафиксированных рейсах StartTask,
+// пакетов и ConfirmCrew. Штрафы за повреждения сюда не входят, а удержанные из оплаты
+// грузчиков вычитаются.
+var CapitalSpent = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "customers",
+	Name:      "capital_spent_total",
+	Help:      "Customer capital paid to crews on committed trips.",
+})
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ApplicationPending  = "pending"
+	ApplicationAccepted = "accepted"
+	ApplicationRejected = "rejected"
+)
+
+// Application - отклик грузчика на опубликованную задачу с желаемой оплатой за рейс.
+type Application struct {
+	ApplicationID uuid.UUID `json:"application_id"`
+	TaskID        uuid.UUID `json:"task_id"`
+	LoaderID      uuid.UUID `json:"loader_id"`
+	AskingPrice   int       `json:"asking_price"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type PublishTaskRequest struct {
+	User   *User
+	TaskID uuid.UUID `json:"task_id"`
+	// Version - ожидаемая версия задачи из If-Match, nil - без проверки
+	Version *int `json:"-"`
+}
+
+type ApplyRequest struct {
+	User        *User
+	TaskID      uuid.UUID `json:"task_id"`
+	AskingPrice int       `json:"asking_price"`
+}
+
+type ConfirmCrewRequest struct {
+	User           *User
+	TaskID         uuid.UUID   `json:"task_id"`
+	ApplicationIDs []uuid.UUID `json:"application_ids"`
+	// Version - ожидаемая версия задачи из If-Match, nil - без проверки
+	Version *int `json:"-"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ContractPerTask  = "per_task"
+	ContractRetainer = "retainer"
+)
+
+const (
+	ContractOffered    = "offered"
+	ContractActive     = "active"
+	ContractDeclined   = "declined"
+	ContractTerminated = "terminated"
+)
+
+// Contract - договор найма грузчика заказчиком.
+// Для ContractPerTask Rate - оплата за каждый рейс (0 - рыночная цена на момент назначения),
+// для ContractRetainer - разовая плата при заключении договора, после которой рейсы не оплачиваются.
+type Contract struct {
+	ContractID uuid.UUID `json:"contract_id"`
+	CustomerID uuid.UUID `json:"customer_id"`
+	LoaderID   uuid.UUID `json:"loader_id"`
+	Kind       string    `json:"kind"`
+	Rate       int       `json:"rate"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// MarketRate сообщает, что оплата за рейс определяется рыночной ценой.
+func (c *Contract) MarketRate() bool {
+	return c.Kind == ContractPerTask && c.Rate == 0
+}
+
+// TaskCost - сколько заказчик платит грузчику за один рейс по договору
+// с фиксированной оплатой.
+func (c *Contract) TaskCost() int {
+	if c.Kind == ContractRetainer {
+		return 0
+	}
+	return c.Rate
+}
+
+type OfferContractRequest struct {
+	User     *User
+	LoaderID uuid.UUID `json:"loader_id"`
+	Kind     string    `json:"kind"`
+	Rate     int       `json:"rate"`
+}
+
+type RosterEntry struct {
+	Contract Contract `json:"contract"`
+	Loader   Loader   `json:"loader"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Review - оценка участника выполненной задачи другим участником: заказчик оценивает
+// грузчиков, грузчик - заказчика.
+type Review struct {
+	ReviewID  uuid.UUID `json:"review_id"`
+	TaskID    uuid.UUID `json:"task_id"`
+	AuthorID  uuid.UUID `json:"author_id"`
+	TargetID  uuid.UUID `json:"target_id"`
+	Rating    int       `json:"rating"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateReviewRequest struct {
+	User     *User
+	TaskID   uuid.UUID `json:"task_id"`
+	TargetID uuid.UUID `json:"target_id"`
+	Rating   int       `json:"rating"`
+	Comment  string    `json:"comment"`
+}
+
+// TaskParticipants - заказчик и грузчики, работавшие над задачей.
+type TaskParticipants struct {
+	CustomerID uuid.UUID
+	Completed  bool
+	LoaderIDs  []uuid.UUID
+}
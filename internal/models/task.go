@@ -1,22 +1,109 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Task struct {
-	TaskID      uuid.UUID `json:"task_id"`
-	CustomerID  uuid.UUID `json:"customer_id"`
-	Weight      int       `json:"weight"`
-	Description string    `json:"description"`
-	Status      bool      `json:"status"`
+	TaskID          uuid.UUID  `json:"task_id"`
+	CustomerID      uuid.UUID  `json:"customer_id"`
+	Weight          int        `json:"weight"`
+	RemainingWeight int        `json:"remaining_weight"`
+	CargoType       string     `json:"cargo_type"`
+	Description     string     `json:"description"`
+	Status          bool       `json:"status"`
+	Priority        int        `json:"priority"`
+	Deadline        *time.Time `json:"deadline,omitempty"`
+	DamagePenalty   int        `json:"damage_penalty"`
+	Published       bool       `json:"published"`
+	Version         int        `json:"version"`
 }
 
 type TaskLoader struct {
 	TaskID   uuid.UUID `json:"task_id"`
 	LoaderID uuid.UUID `json:"loader_id"`
+	Price    int       `json:"price"`
+}
+
+// Quote - текущая цена грузчика за рейс.
+type Quote struct {
+	LoaderID uuid.UUID `json:"loader_id"`
+	Price    int       `json:"price"`
+}
+
+// DamageEvent - порча груза грузчиком во время рейса.
+type DamageEvent struct {
+	DamageID  uuid.UUID `json:"damage_id"`
+	TaskID    uuid.UUID `json:"task_id"`
+	LoaderID  uuid.UUID `json:"loader_id"`
+	Penalty   int       `json:"penalty"`
+	ChargedTo string    `json:"charged_to"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type StartTaskRequest struct {
 	User      *User
 	TaskID    uuid.UUID   `json:"task_id"`
 	LoaderIDs []uuid.UUID `json:"loader_ids"`
+	// Version - ожидаемая версия задачи из If-Match, nil - без проверки
+	Version *int `json:"-"`
+}
+
+const (
+	BatchModeAllOrNothing = "all_or_nothing"
+	BatchModeBestEffort   = "best_effort"
+)
+
+type BatchStartTaskRequest struct {
+	User  *User
+	Mode  string             `json:"mode"`
+	Tasks []StartTaskRequest `json:"tasks"`
+}
+
+type BatchStartTaskResult struct {
+	Committed bool                       `json:"committed"`
+	Results   []BatchStartTaskItemResult `json:"results"`
+}
+
+type BatchStartTaskItemResult struct {
+	TaskID  uuid.UUID `json:"task_id"`
+	Started bool      `json:"started"`
+	Error   string    `json:"error,omitempty"`
+}
+
+type SetTaskPriorityRequest struct {
+	User     *User
+	TaskID   uuid.UUID  `json:"task_id"`
+	Priority int        `json:"priority"`
+	Deadline *time.Time `json:"deadline"`
+	// Version - ожидаемая версия задачи из If-Match, nil - без проверки
+	Version *int `json:"-"`
+}
+
+// DispatchResult - итог автоматического запуска очереди задач заказчика.
+type DispatchResult struct {
+	Started []uuid.UUID    `json:"started"`
+	Skipped []DispatchSkip `json:"skipped"`
+}
+
+type DispatchSkip struct {
+	TaskID uuid.UUID `json:"task_id"`
+	Reason string    `json:"reason"`
+}
+
+// Plan - план распределения грузчиков по задачам заказчика.
+type Plan struct {
+	Assignments []PlanAssignment `json:"assignments"`
+	TotalCost   int              `json:"total_cost"`
+	CapitalLeft int              `json:"capital_left"`
+}
+
+type PlanAssignment struct {
+	TaskID    uuid.UUID   `json:"task_id"`
+	LoaderIDs []uuid.UUID `json:"loader_ids"`
+	Cost      int         `json:"cost"`
+	// Partial - рейс только уменьшает оставшийся вес: штат не может закончить задачу за один рейс
+	Partial bool `json:"partial"`
 }
@@ -1,23 +1,63 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"time"
+)
 
 type User struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Username string    `json:"username"`
 	Password string    `json:"password"` // Храните хешированный пароль
 	UserType string    `json:"user_type"`
+	// Ruleset - набор правил игры, выбирается заказчиком при регистрации
+	Ruleset string `json:"ruleset,omitempty"`
 }
 
 type Customer struct {
-	CustomerID uuid.UUID `json:"customer_id"`
-	Capital    int       `json:"capital"`
+	CustomerID  uuid.UUID `json:"customer_id"`
+	Capital     int       `json:"capital"`
+	Ruleset     string    `json:"ruleset"`
+	RatingAvg   float64   `json:"rating_avg"`
+	RatingCount int       `json:"rating_count"`
+	Version     int       `json:"version"`
 }
 
 type Loader struct {
-	LoaderID  uuid.UUID `json:"loader_id"`
-	MaxWeight int       `json:"max_weight"`
-	Drunk     bool      `json:"drunk"`
-	Fatigue   int       `json:"fatigue"`
-	Salary    int       `json:"salary"`
+	LoaderID    uuid.UUID `json:"loader_id"`
+	MaxWeight   int       `json:"max_weight"`
+	Drunk       bool      `json:"drunk"`
+	Fatigue     int       `json:"fatigue"`
+	Salary      int       `json:"salary"`
+	Experience  int       `json:"experience"`
+	Level       int       `json:"level"`
+	RatingAvg   float64   `json:"rating_avg"`
+	RatingCount int       `json:"rating_count"`
+	Version     int       `json:"version"`
+}
+
+// CustomerDetails - профиль заказчика в GET /me.
+type CustomerDetails struct {
+	Info     *Customer `json:"info"`
+	Loaders  []Loader  `json:"loaders"`
+	GameTime time.Time `json:"game_time"`
+}
+
+// LoaderDetails - профиль грузчика в GET /me.
+type LoaderDetails struct {
+	*Loader
+	NextLevelExperience *int      `json:"next_level_experience,omitempty"`
+	GameTime            time.Time `json:"game_time"`
+}
+
+const (
+	LoaderSortRating    = "rating"
+	LoaderSortSalary    = "salary"
+	LoaderSortMaxWeight = "max_weight"
+)
+
+// LoaderFilter - условия выборки и сортировки списка грузчиков.
+type LoaderFilter struct {
+	MinRating *float64
+	Sort      string
 }
@@ -0,0 +1,8 @@
+package models
+
+import "errors"
+
+// ErrVersionMismatch - строка изменена после того, как клиент или транзакция ее прочитали.
+// Заказчики, грузчики и задачи хранят версию, которая растет при каждом обновлении;
+// обновление с устаревшей версией не применяется.
+var ErrVersionMismatch = errors.New("version mismatch")
@@ -0,0 +1,122 @@
+package applicationRepository
+
+import (
+	"context"
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const applicationColumns = `application_id, task_id, loader_id, asking_price, status, created_at`
+
+type ApplicationRepository struct {
+	db *postgres.Postgres
+}
+
+func NewApplicationRepository(db *postgres.Postgres) *ApplicationRepository {
+	return &ApplicationRepository{db: db}
+}
+
+func (r *ApplicationRepository) CreateApplication(ctx context.Context, application *models.Application) (*models.Application, error) {
+	const query = `
+		INSERT INTO applications (task_id, loader_id, asking_price, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING application_id, created_at`
+
+	err := r.db.Querier(ctx).QueryRow(ctx, query, application.TaskID, application.LoaderID, application.AskingPrice, application.Status).Scan(&application.ApplicationID, &application.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return application, nil
+}
+
+// GetApplicationsCustomers возвращает отклики на задачу, если она принадлежит заказчику.
+func (r *ApplicationRepository) GetApplicationsCustomers(ctx context.Context, customerID uuid.UUID, taskID uuid.UUID) ([]models.Application, error) {
+	const query = `SELECT a.application_id, a.task_id, a.loader_id, a.asking_price, a.status, a.created_at
+                   FROM applications a
+                   JOIN tasks t ON t.task_id = a.task_id
+                   WHERE t.customer_id = $1 AND a.task_id = $2
+                   ORDER BY a.created_at`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, customerID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanApplications(rows)
+}
+
+func (r *ApplicationRepository) GetApplicationsLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Application, error) {
+	const query = `SELECT ` + applicationColumns + ` FROM applications WHERE loader_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, loaderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanApplications(rows)
+}
+
+func (r *ApplicationRepository) GetApplicationsByIDsForUpdate(ctx context.Context, applicationIDs []uuid.UUID) ([]models.Application, error) {
+	const query = `SELECT ` + applicationColumns + ` FROM applications WHERE application_id = ANY($1) ORDER BY application_id FOR UPDATE`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, applicationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	applications, err := scanApplications(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(applications) != len(applicationIDs) {
+		return nil, errors.New("wrong applications")
+	}
+
+	return applications, nil
+}
+
+// CloseApplications переводит отобранные отклики в accepted, а при закрытии задачи
+// отклоняет все остальные ожидающие отклики на нее.
+func (r *ApplicationRepository) CloseApplications(ctx context.Context, taskID uuid.UUID, acceptedIDs []uuid.UUID, rejectRest bool) error {
+	const acceptQuery = `UPDATE applications SET status = 'accepted' WHERE application_id = ANY($1)`
+	const rejectQuery = `UPDATE applications SET status = 'rejected' WHERE task_id = $1 AND status = 'pending'`
+
+	_, err := r.db.Querier(ctx).Exec(ctx, acceptQuery, acceptedIDs)
+	if err != nil {
+		return err
+	}
+
+	if rejectRest {
+		_, err = r.db.Querier(ctx).Exec(ctx, rejectQuery, taskID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanApplications(rows pgx.Rows) ([]models.Application, error) {
+	defer rows.Close()
+
+	var applications []models.Application
+	for rows.Next() {
+		var application models.Application
+		err := rows.Scan(&application.ApplicationID, &application.TaskID, &application.LoaderID, &application.AskingPrice, &application.Status, &application.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		applications = append(applications, application)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applications, nil
+}
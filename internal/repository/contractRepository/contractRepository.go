@@ -0,0 +1,143 @@
+package contractRepository
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const contractColumns = `contract_id, customer_id, loader_id, kind, rate, status, created_at`
+
+type ContractRepository struct {
+	db *postgres.Postgres
+}
+
+func NewContractRepository(db *postgres.Postgres) *ContractRepository {
+	return &ContractRepository{db: db}
+}
+
+func (r *ContractRepository) CreateContract(ctx context.Context, contract *models.Contract) (*models.Contract, error) {
+	const query = `
+		INSERT INTO contracts (customer_id, loader_id, kind, rate, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING contract_id, created_at`
+
+	err := r.db.Querier(ctx).QueryRow(ctx, query, contract.CustomerID, contract.LoaderID, contract.Kind, contract.Rate, contract.Status).Scan(&contract.ContractID, &contract.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return contract, nil
+}
+
+func (r *ContractRepository) GetContractByIDForUpdate(ctx context.Context, contractID uuid.UUID) (*models.Contract, error) {
+	const query = `SELECT ` + contractColumns + ` FROM contracts WHERE contract_id = $1 FOR UPDATE`
+
+	var contract models.Contract
+	err := r.db.Querier(ctx).QueryRow(ctx, query, contractID).Scan(&contract.ContractID, &contract.CustomerID, &contract.LoaderID, &contract.Kind, &contract.Rate, &contract.Status, &contract.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contract, nil
+}
+
+func (r *ContractRepository) UpdateContract(ctx context.Context, contract *models.Contract) error {
+	const query = `UPDATE contracts SET kind = $1, rate = $2, status = $3 WHERE contract_id = $4`
+
+	_, err := r.db.Querier(ctx).Exec(ctx, query, contract.Kind, contract.Rate, contract.Status, contract.ContractID)
+	return err
+}
+
+func (r *ContractRepository) GetContractsCustomers(ctx context.Context, customerID uuid.UUID) ([]models.Contract, error) {
+	const query = `SELECT ` + contractColumns + ` FROM contracts WHERE customer_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanContracts(rows)
+}
+
+func (r *ContractRepository) GetContractsLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Contract, error) {
+	const query = `SELECT ` + contractColumns + ` FROM contracts WHERE loader_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, loaderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanContracts(rows)
+}
+
+// GetActiveContracts возвращает действующие договоры заказчика с перечисленными грузчиками
+// и не дает расторгнуть их до конца транзакции.
+func (r *ContractRepository) GetActiveContracts(ctx context.Context, customerID uuid.UUID, loaderIDs []uuid.UUID) ([]models.Contract, error) {
+	const query = `SELECT ` + contractColumns + ` FROM contracts
+                   WHERE customer_id = $1 AND loader_id = ANY($2) AND status = 'active'
+                   ORDER BY contract_id FOR SHARE`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, customerID, loaderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanContracts(rows)
+}
+
+// GetRoster возвращает грузчиков, работающих у заказчика по действующим договорам.
+func (r *ContractRepository) GetRoster(ctx context.Context, customerID uuid.UUID) ([]models.RosterEntry, error) {
+	const query = `SELECT c.contract_id, c.customer_id, c.loader_id, c.kind, c.rate, c.status, c.created_at,
+                          l.loader_id, l.max_weight, l.drunk, l.fatigue, l.salary, l.experience, l.level
+                   FROM contracts c
+                   JOIN loaders l ON l.loader_id = c.loader_id
+                   WHERE c.customer_id = $1 AND c.status = 'active'
+                   ORDER BY c.created_at`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roster []models.RosterEntry
+	for rows.Next() {
+		var entry models.RosterEntry
+		contract, loader := &entry.Contract, &entry.Loader
+		err = rows.Scan(&contract.ContractID, &contract.CustomerID, &contract.LoaderID, &contract.Kind, &contract.Rate, &contract.Status, &contract.CreatedAt,
+			&loader.LoaderID, &loader.MaxWeight, &loader.Drunk, &loader.Fatigue, &loader.Salary, &loader.Experience, &loader.Level)
+		if err != nil {
+			return nil, err
+		}
+		roster = append(roster, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roster, nil
+}
+
+func scanContracts(rows pgx.Rows) ([]models.Contract, error) {
+	defer rows.Close()
+
+	var contracts []models.Contract
+	for rows.Next() {
+		var contract models.Contract
+		err := rows.Scan(&contract.ContractID, &contract.CustomerID, &contract.LoaderID, &contract.Kind, &contract.Rate, &contract.Status, &contract.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, contract)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return contracts, nil
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/AhegaoHD/WBT/internal/models"
 	"github.com/AhegaoHD/WBT/pkg/postgres"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 )
 
 type CustomerRepository struct {
@@ -16,49 +15,59 @@ func NewCustomerRepository(db *postgres.Postgres) *CustomerRepository {
 	return &CustomerRepository{db: db}
 }
 
-func (r *CustomerRepository) CreateCustomer(ctx context.Context, customer *models.Customer, tx pgx.Tx) error {
-	const query = `INSERT INTO customers (customer_id, capital) VALUES ($1, $2)`
-	_, err := tx.Exec(ctx, query, customer.CustomerID, customer.Capital)
+func (r *CustomerRepository) CreateCustomer(ctx context.Context, customer *models.Customer) error {
+	const query = `INSERT INTO customers (customer_id, capital, ruleset) VALUES ($1, $2, $3)`
+	_, err := r.db.Querier(ctx).Exec(ctx, query, customer.CustomerID, customer.Capital, customer.Ruleset)
 	return err
 }
 
 func (r *CustomerRepository) GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
-	const query = `SELECT customer_id, capital FROM customers WHERE customer_id = $1`
+	const query = `SELECT c.customer_id, c.capital, c.ruleset, c.version, COALESCE(AVG(rv.rating)::float8, 0), COUNT(rv.rating)
+                   FROM customers c
+                   LEFT JOIN reviews rv ON rv.target_id = c.customer_id
+                   WHERE c.customer_id = $1
+                   GROUP BY c.customer_id`
 	var customer models.Customer
-	err := r.db.Pool.QueryRow(ctx, query, customerID).Scan(&customer.CustomerID, &customer.Capital)
+	err := r.db.Querier(ctx).QueryRow(ctx, query, customerID).Scan(&customer.CustomerID, &customer.Capital, &customer.Ruleset, &customer.Version, &customer.RatingAvg, &customer.RatingCount)
 	if err != nil {
 		return nil, err
 	}
 	return &customer, nil
 }
 
-func (r *CustomerRepository) HasCustomers(ctx context.Context, tx pgx.Tx) (bool, error) {
+func (r *CustomerRepository) HasCustomers(ctx context.Context) (bool, error) {
 	const query = `SELECT COUNT(*) FROM customers`
 	var count int
-	err := tx.QueryRow(ctx, query).Scan(&count)
+	err := r.db.Querier(ctx).QueryRow(ctx, query).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-func (r *CustomerRepository) GetCustomerByIDForUpdate(ctx context.Context, customerID uuid.UUID, tx pgx.Tx) (*models.Customer, error) {
-	const query = `SELECT customer_id, capital FROM customers WHERE customer_id = $1 FOR UPDATE `
+func (r *CustomerRepository) GetCustomerByIDForUpdate(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
+	const query = `SELECT customer_id, capital, ruleset, version FROM customers WHERE customer_id = $1 FOR UPDATE `
 	var customer models.Customer
-	err := tx.QueryRow(ctx, query, customerID).Scan(&customer.CustomerID, &customer.Capital)
+	err := r.db.Querier(ctx).QueryRow(ctx, query, customerID).Scan(&customer.CustomerID, &customer.Capital, &customer.Ruleset, &customer.Version)
 	if err != nil {
 		return nil, err
 	}
 	return &customer, nil
 }
 
-func (r *CustomerRepository) UpdateCustomer(ctx context.Context, customer *models.Customer, tx pgx.Tx) error {
-	const query = `UPDATE customers SET capital = $1 WHERE customer_id = $2`
+// UpdateCustomer сохраняет заказчика, если его версия не изменилась с момента чтения,
+// и увеличивает customer.Version.
+func (r *CustomerRepository) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
+	const query = `UPDATE customers SET capital = $1, version = version + 1 WHERE customer_id = $2 AND version = $3`
 
-	_, err := tx.Exec(ctx, query, customer.Capital, customer.CustomerID)
+	tag, err := r.db.Querier(ctx).Exec(ctx, query, customer.Capital, customer.CustomerID, customer.Version)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrVersionMismatch
+	}
 
+	customer.Version++
 	return nil
 }
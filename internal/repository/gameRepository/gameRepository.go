@@ -0,0 +1,57 @@
+package gameRepository
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/pkg/postgres"
+	"time"
+)
+
+type GameRepository struct {
+	db *postgres.Postgres
+}
+
+func NewGameRepository(db *postgres.Postgres) *GameRepository {
+	return &GameRepository{db: db}
+}
+
+// GetOrCreateSeed возвращает seed игры. Если игра еще не создана, она создается с переданным seed.
+func (r *GameRepository) GetOrCreateSeed(ctx context.Context, seed int64) (int64, error) {
+	const insert = `INSERT INTO games (seed) VALUES ($1) ON CONFLICT (game_id) DO NOTHING`
+	const query = `SELECT seed FROM games`
+
+	_, err := r.db.Querier(ctx).Exec(ctx, insert, seed)
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.db.Querier(ctx).QueryRow(ctx, query).Scan(&seed)
+	if err != nil {
+		return 0, err
+	}
+	return seed, nil
+}
+
+// GetEpoch возвращает время создания игры - начало отсчета игровых часов.
+func (r *GameRepository) GetEpoch(ctx context.Context) (time.Time, error) {
+	const query = `SELECT created_at FROM games`
+
+	var epoch time.Time
+	err := r.db.Querier(ctx).QueryRow(ctx, query).Scan(&epoch)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return epoch, nil
+}
+
+// NextRegistration увеличивает счетчик регистраций игры и возвращает его новое значение.
+// Строка игры блокируется до конца транзакции, так что номера не повторяются.
+func (r *GameRepository) NextRegistration(ctx context.Context) (int64, error) {
+	const query = `UPDATE games SET registrations = registrations + 1 RETURNING registrations`
+
+	var n int64
+	err := r.db.Querier(ctx).QueryRow(ctx, query).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
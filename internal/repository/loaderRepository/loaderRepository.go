@@ -9,6 +9,8 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+const loaderColumns = `loader_id, max_weight, drunk, fatigue, salary, experience, level`
+
 type LoaderRepository struct {
 	db *postgres.Postgres
 }
@@ -17,16 +19,30 @@ func NewLoaderRepository(db *postgres.Postgres) *LoaderRepository {
 	return &LoaderRepository{db: db}
 }
 
-func (r *LoaderRepository) CreateLoader(ctx context.Context, loader *models.Loader, tx pgx.Tx) error {
-	const query = `INSERT INTO loaders (loader_id, max_weight, drunk, fatigue, salary) VALUES ($1, $2, $3, $4, $5)`
-	_, err := tx.Exec(ctx, query, loader.LoaderID, loader.MaxWeight, loader.Drunk, loader.Fatigue, loader.Salary)
+func (r *LoaderRepository) CreateLoader(ctx context.Context, loader *models.Loader) error {
+	const query = `INSERT INTO loaders (` + loaderColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.Querier(ctx).Exec(ctx, query, loader.LoaderID, loader.MaxWeight, loader.Drunk, loader.Fatigue, loader.Salary, loader.Experience, loader.Level)
 	return err
 }
 
+// ratedLoaders - грузчики вместе со средней оценкой и количеством отзывов о них.
+const ratedLoaders = `SELECT l.loader_id, l.max_weight, l.drunk, l.fatigue, l.salary, l.experience, l.level, l.version,
+                             COALESCE(rv.rating_avg, 0), COALESCE(rv.rating_count, 0)
+                      FROM loaders l
+                      LEFT JOIN (SELECT target_id, AVG(rating)::float8 AS rating_avg, COUNT(*) AS rating_count
+                                 FROM reviews GROUP BY target_id) rv ON rv.target_id = l.loader_id`
+
+var loaderSorts = map[string]string{
+	"":                         ` ORDER BY l.loader_id`,
+	models.LoaderSortRating:    ` ORDER BY COALESCE(rv.rating_avg, 0) DESC, COALESCE(rv.rating_count, 0) DESC, l.loader_id`,
+	models.LoaderSortSalary:    ` ORDER BY l.salary, l.loader_id`,
+	models.LoaderSortMaxWeight: ` ORDER BY l.max_weight DESC, l.loader_id`,
+}
+
 func (r *LoaderRepository) GetLoaderByID(ctx context.Context, loaderID uuid.UUID) (*models.Loader, error) {
-	const query = `SELECT loader_id, max_weight, drunk, fatigue, salary FROM loaders WHERE loader_id = $1`
+	const query = ratedLoaders + ` WHERE l.loader_id = $1`
 	var loader models.Loader
-	err := r.db.Pool.QueryRow(ctx, query, loaderID).Scan(&loader.LoaderID, &loader.MaxWeight, &loader.Drunk, &loader.Fatigue, &loader.Salary)
+	err := r.db.Querier(ctx).QueryRow(ctx, query, loaderID).Scan(&loader.LoaderID, &loader.MaxWeight, &loader.Drunk, &loader.Fatigue, &loader.Salary, &loader.Experience, &loader.Level, &loader.Version, &loader.RatingAvg, &loader.RatingCount)
 	if err != nil {
 		return nil, err
 	}
@@ -34,8 +50,18 @@ func (r *LoaderRepository) GetLoaderByID(ctx context.Context, loaderID uuid.UUID
 }
 
 func (r *LoaderRepository) GetLoaders(ctx context.Context) ([]models.Loader, error) {
-	const query = `SELECT loader_id, max_weight, drunk, fatigue, salary FROM loaders`
-	rows, err := r.db.Pool.Query(ctx, query)
+	return r.FindLoaders(ctx, &models.LoaderFilter{})
+}
+
+// FindLoaders возвращает грузчиков с рейтингом не ниже filter.MinRating в порядке filter.Sort.
+func (r *LoaderRepository) FindLoaders(ctx context.Context, filter *models.LoaderFilter) ([]models.Loader, error) {
+	order, ok := loaderSorts[filter.Sort]
+	if !ok {
+		return nil, errors.New("unknown sort")
+	}
+	query := ratedLoaders + ` WHERE $1::float8 IS NULL OR COALESCE(rv.rating_avg, 0) >= $1` + order
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, filter.MinRating)
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +70,7 @@ func (r *LoaderRepository) GetLoaders(ctx context.Context) ([]models.Loader, err
 	var loaders []models.Loader
 	for rows.Next() {
 		var loader models.Loader
-		err = rows.Scan(&loader.LoaderID, &loader.MaxWeight, &loader.Drunk, &loader.Fatigue, &loader.Salary)
+		err = rows.Scan(&loader.LoaderID, &loader.MaxWeight, &loader.Drunk, &loader.Fatigue, &loader.Salary, &loader.Experience, &loader.Level, &loader.Version, &loader.RatingAvg, &loader.RatingCount)
 		if err != nil {
 			return nil, err
 		}
@@ -59,11 +85,29 @@ func (r *LoaderRepository) GetLoaders(ctx context.Context) ([]models.Loader, err
 	return loaders, nil
 }
 
-func (r *LoaderRepository) GetLoadersByIDsForUpdate(ctx context.Context, loaderIDs []uuid.UUID, tx pgx.Tx) ([]models.Loader, error) {
+// GetDemand возвращает долю грузчиков, занятых по действующим договорам.
+func (r *LoaderRepository) GetDemand(ctx context.Context) (float64, error) {
+	const query = `SELECT COUNT(*), COUNT(c.loader_id)
+                   FROM loaders l
+                   LEFT JOIN contracts c ON c.loader_id = l.loader_id AND c.status = 'active'`
+
+	var total, busy int
+	err := r.db.Querier(ctx).QueryRow(ctx, query).Scan(&total, &busy)
+	if err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	return float64(busy) / float64(total), nil
+}
 
-	const query = `SELECT loader_id, max_weight, drunk, fatigue, salary FROM loaders WHERE loader_id = ANY($1) FOR UPDATE `
+// GetLoadersByIDsForUpdate блокирует грузчиков в порядке loader_id, чтобы параллельные
+// транзакции с пересекающимися наборами грузчиков не попадали в deadlock.
+func (r *LoaderRepository) GetLoadersByIDsForUpdate(ctx context.Context, loaderIDs []uuid.UUID) ([]models.Loader, error) {
+	const query = `SELECT ` + loaderColumns + `, version FROM loaders WHERE loader_id = ANY($1) ORDER BY loader_id FOR UPDATE`
 
-	rows, err := tx.Query(ctx, query, loaderIDs)
+	rows, err := r.db.Querier(ctx).Query(ctx, query, loaderIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +116,7 @@ func (r *LoaderRepository) GetLoadersByIDsForUpdate(ctx context.Context, loaderI
 	var loaders []models.Loader
 	for rows.Next() {
 		var loader models.Loader
-		err = rows.Scan(&loader.LoaderID, &loader.MaxWeight, &loader.Drunk, &loader.Fatigue, &loader.Salary)
+		err = rows.Scan(&loader.LoaderID, &loader.MaxWeight, &loader.Drunk, &loader.Fatigue, &loader.Salary, &loader.Experience, &loader.Level, &loader.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -90,24 +134,48 @@ func (r *LoaderRepository) GetLoadersByIDsForUpdate(ctx context.Context, loaderI
 	return loaders, nil
 }
 
-func (r *LoaderRepository) UpdateLoaders(ctx context.Context, loaders []models.Loader, tx pgx.Tx) error {
+// LockLoaders блокирует строки грузчиков в порядке loader_id, чтобы параллельные
+// транзакции, блокирующие пересекающиеся наборы грузчиков, не попадали в deadlock.
+// Несуществующие идентификаторы пропускаются.
+func (r *LoaderRepository) LockLoaders(ctx context.Context, loaderIDs []uuid.UUID) error {
+	const query = `SELECT loader_id FROM loaders WHERE loader_id = ANY($1) ORDER BY loader_id FOR UPDATE`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, loaderIDs)
+	if err != nil {
+		return err
+	}
+	rows.Close()
+
+	return rows.Err()
+}
+
+// UpdateLoaders сохраняет грузчиков, если ни у одного версия не изменилась с момента чтения,
+// и увеличивает их Version.
+func (r *LoaderRepository) UpdateLoaders(ctx context.Context, loaders []models.Loader) error {
 	batch := &pgx.Batch{}
 
-	const query = `UPDATE loaders SET max_weight = $1, drunk = $2, fatigue = $3, salary = $4 WHERE loader_id = $5`
+	const query = `UPDATE loaders SET max_weight = $1, drunk = $2, fatigue = $3, salary = $4, experience = $5, level = $6, version = version + 1
+                   WHERE loader_id = $7 AND version = $8`
 	for _, loader := range loaders {
-		batch.Queue(query, loader.MaxWeight, loader.Drunk, loader.Fatigue, loader.Salary, loader.LoaderID)
+		batch.Queue(query, loader.MaxWeight, loader.Drunk, loader.Fatigue, loader.Salary, loader.Experience, loader.Level, loader.LoaderID, loader.Version)
 	}
 
-	br := tx.SendBatch(ctx, batch)
+	br := r.db.Querier(ctx).SendBatch(ctx, batch)
 	defer br.Close()
 
 	// Проверяем результаты выполнения каждого запроса в пакете
 	for range loaders {
-		_, err := br.Exec()
+		tag, err := br.Exec()
 		if err != nil {
 			return err
 		}
+		if tag.RowsAffected() == 0 {
+			return models.ErrVersionMismatch
+		}
 	}
 
+	for i := range loaders {
+		loaders[i].Version++
+	}
 	return nil
 }
@@ -0,0 +1,145 @@
+package memoryRepository
+
+import (
+	"context"
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/lockorder"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/google/uuid"
+	"sort"
+)
+
+type ApplicationRepository struct {
+	store *Store
+}
+
+func NewApplicationRepository(store *Store) *ApplicationRepository {
+	return &ApplicationRepository{store: store}
+}
+
+func (r *ApplicationRepository) CreateApplication(ctx context.Context, application *models.Application) (*models.Application, error) {
+	err := r.store.exec(ctx, func(t *Tx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		if application.Status == models.ApplicationPending {
+			for _, other := range r.store.apps.rows {
+				if other.Status == models.ApplicationPending && other.TaskID == application.TaskID && other.LoaderID == application.LoaderID {
+					return uniqueViolation("applications_pending_idx")
+				}
+			}
+		}
+		application.ApplicationID = r.store.newID()
+		application.CreatedAt = r.store.now()
+		r.store.apps.put(t, application.ApplicationID, *application)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return application, nil
+}
+
+func (r *ApplicationRepository) GetApplicationsCustomers(ctx context.Context, customerID uuid.UUID, taskID uuid.UUID) ([]models.Application, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	task, _ := r.store.tasks.get(t, taskID)
+	r.store.mu.RUnlock()
+	if task.CustomerID != customerID {
+		return nil, nil
+	}
+
+	return r.selectApplications(t, func(application *models.Application) bool {
+		return application.TaskID == taskID
+	}), nil
+}
+
+func (r *ApplicationRepository) GetApplicationsLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Application, error) {
+	return r.selectApplications(r.store.txFrom(ctx), func(application *models.Application) bool {
+		return application.LoaderID == loaderID
+	}), nil
+}
+
+func (r *ApplicationRepository) GetApplicationsByIDsForUpdate(ctx context.Context, applicationIDs []uuid.UUID) ([]models.Application, error) {
+	ids := lockorder.Sorted(applicationIDs)
+	err := r.store.exec(ctx, func(t *Tx) error {
+		return r.store.lock(ctx, t, tableApplications, ids...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	applications := make([]models.Application, 0, len(ids))
+	for _, id := range ids {
+		application, ok := r.store.apps.get(t, id)
+		if !ok {
+			continue
+		}
+		applications = append(applications, application)
+	}
+	if len(applications) != len(applicationIDs) {
+		return nil, errors.New("wrong applications")
+	}
+	return applications, nil
+}
+
+func (r *ApplicationRepository) CloseApplications(ctx context.Context, taskID uuid.UUID, acceptedIDs []uuid.UUID, rejectRest bool) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		accepted := make(map[uuid.UUID]bool, len(acceptedIDs))
+		for _, id := range acceptedIDs {
+			accepted[id] = true
+		}
+		rejected := r.selectApplications(t, func(application *models.Application) bool {
+			return rejectRest && application.TaskID == taskID && application.Status == models.ApplicationPending && !accepted[application.ApplicationID]
+		})
+
+		err := r.store.lock(ctx, t, tableApplications, lockorder.Sorted(acceptedIDs)...)
+		if err != nil {
+			return err
+		}
+		for i := range rejected {
+			err = r.store.lock(ctx, t, tableApplications, rejected[i].ApplicationID)
+			if err != nil {
+				return err
+			}
+		}
+
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		for id := range accepted {
+			if application, ok := r.store.apps.get(t, id); ok {
+				application.Status = models.ApplicationAccepted
+				r.store.apps.put(t, id, application)
+			}
+		}
+		for i := range rejected {
+			application, _ := r.store.apps.get(t, rejected[i].ApplicationID)
+			if application.Status == models.ApplicationPending {
+				application.Status = models.ApplicationRejected
+				r.store.apps.put(t, application.ApplicationID, application)
+			}
+		}
+		return nil
+	})
+}
+
+// selectApplications возвращает отклики в порядке создания.
+func (r *ApplicationRepository) selectApplications(t *Tx, match func(application *models.Application) bool) []models.Application {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var applications []models.Application
+	for _, application := range r.store.apps.visible(t) {
+		if match(&application) {
+			applications = append(applications, application)
+		}
+	}
+	sort.Slice(applications, func(i, j int) bool {
+		return lessID(applications[i].ApplicationID, applications[j].ApplicationID)
+	})
+	return applications
+}
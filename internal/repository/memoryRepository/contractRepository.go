@@ -0,0 +1,174 @@
+package memoryRepository
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"sort"
+)
+
+type ContractRepository struct {
+	store *Store
+}
+
+func NewContractRepository(store *Store) *ContractRepository {
+	return &ContractRepository{store: store}
+}
+
+func (r *ContractRepository) CreateContract(ctx context.Context, contract *models.Contract) (*models.Contract, error) {
+	err := r.store.exec(ctx, func(t *Tx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		err := r.checkUnique(contract)
+		if err != nil {
+			return err
+		}
+		contract.ContractID = r.store.newID()
+		contract.CreatedAt = r.store.now()
+		r.store.contracts.put(t, contract.ContractID, *contract)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return contract, nil
+}
+
+func (r *ContractRepository) GetContractByIDForUpdate(ctx context.Context, contractID uuid.UUID) (*models.Contract, error) {
+	err := r.store.exec(ctx, func(t *Tx) error {
+		return r.store.lock(ctx, t, tableContracts, contractID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	contract, ok := r.store.contracts.get(t, contractID)
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &contract, nil
+}
+
+func (r *ContractRepository) UpdateContract(ctx context.Context, contract *models.Contract) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		err := r.store.lock(ctx, t, tableContracts, contract.ContractID)
+		if err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		row, ok := r.store.contracts.get(t, contract.ContractID)
+		if !ok {
+			return nil
+		}
+		row.Kind, row.Rate, row.Status = contract.Kind, contract.Rate, contract.Status
+		err = r.checkUnique(&row)
+		if err != nil {
+			return err
+		}
+		r.store.contracts.put(t, row.ContractID, row)
+		return nil
+	})
+}
+
+func (r *ContractRepository) GetContractsCustomers(ctx context.Context, customerID uuid.UUID) ([]models.Contract, error) {
+	return r.selectContracts(r.store.txFrom(ctx), func(contract *models.Contract) bool {
+		return contract.CustomerID == customerID
+	}), nil
+}
+
+func (r *ContractRepository) GetContractsLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Contract, error) {
+	return r.selectContracts(r.store.txFrom(ctx), func(contract *models.Contract) bool {
+		return contract.LoaderID == loaderID
+	}), nil
+}
+
+// GetActiveContracts блокирует найденные договоры; FOR SHARE эмулируется исключительной блокировкой.
+func (r *ContractRepository) GetActiveContracts(ctx context.Context, customerID uuid.UUID, loaderIDs []uuid.UUID) ([]models.Contract, error) {
+	wanted := make(map[uuid.UUID]bool, len(loaderIDs))
+	for _, loaderID := range loaderIDs {
+		wanted[loaderID] = true
+	}
+	match := func(contract *models.Contract) bool {
+		return contract.CustomerID == customerID && wanted[contract.LoaderID] && contract.Status == models.ContractActive
+	}
+
+	t := r.store.txFrom(ctx)
+	contracts := r.selectContracts(t, match)
+	err := r.store.exec(ctx, func(t *Tx) error {
+		for i := range contracts {
+			err := r.store.lock(ctx, t, tableContracts, contracts[i].ContractID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Пока ждали блокировку, договор могли расторгнуть
+	return r.selectContracts(t, match), nil
+}
+
+func (r *ContractRepository) GetRoster(ctx context.Context, customerID uuid.UUID) ([]models.RosterEntry, error) {
+	t := r.store.txFrom(ctx)
+	contracts := r.selectContracts(t, func(contract *models.Contract) bool {
+		return contract.CustomerID == customerID && contract.Status == models.ContractActive
+	})
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var roster []models.RosterEntry
+	for _, contract := range contracts {
+		loader, ok := r.store.loaders.get(t, contract.LoaderID)
+		if !ok {
+			continue
+		}
+		loader.RatingAvg, loader.RatingCount = 0, 0
+		roster = append(roster, models.RosterEntry{Contract: contract, Loader: loader})
+	}
+	return roster, nil
+}
+
+// checkUnique повторяет уникальные индексы contracts. Вызывается под store.mu.
+func (r *ContractRepository) checkUnique(contract *models.Contract) error {
+	open := contract.Status == models.ContractOffered || contract.Status == models.ContractActive
+	for _, other := range r.store.contracts.rows {
+		if other.ContractID == contract.ContractID {
+			continue
+		}
+		if contract.Status == models.ContractActive && other.Status == models.ContractActive && other.LoaderID == contract.LoaderID {
+			return uniqueViolation("contracts_active_loader_idx")
+		}
+		otherOpen := other.Status == models.ContractOffered || other.Status == models.ContractActive
+		if open && otherOpen && other.CustomerID == contract.CustomerID && other.LoaderID == contract.LoaderID {
+			return uniqueViolation("contracts_open_offer_idx")
+		}
+	}
+	return nil
+}
+
+// selectContracts возвращает договоры в порядке создания.
+func (r *ContractRepository) selectContracts(t *Tx, match func(contract *models.Contract) bool) []models.Contract {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var contracts []models.Contract
+	for _, contract := range r.store.contracts.visible(t) {
+		if match(&contract) {
+			contracts = append(contracts, contract)
+		}
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return lessID(contracts[i].ContractID, contracts[j].ContractID)
+	})
+	return contracts
+}
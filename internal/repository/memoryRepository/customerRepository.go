@@ -0,0 +1,91 @@
+package memoryRepository
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type CustomerRepository struct {
+	store *Store
+}
+
+func NewCustomerRepository(store *Store) *CustomerRepository {
+	return &CustomerRepository{store: store}
+}
+
+func (r *CustomerRepository) CreateCustomer(ctx context.Context, customer *models.Customer) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		if _, ok := r.store.customers.rows[customer.CustomerID]; ok {
+			return uniqueViolation("customers_pkey")
+		}
+		r.store.customers.put(t, customer.CustomerID, models.Customer{CustomerID: customer.CustomerID, Capital: customer.Capital, Ruleset: customer.Ruleset, Version: 1})
+		return nil
+	})
+}
+
+func (r *CustomerRepository) GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	customer, ok := r.store.customers.get(t, customerID)
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	customer.RatingAvg, customer.RatingCount = r.store.rating(t, customerID)
+	return &customer, nil
+}
+
+func (r *CustomerRepository) HasCustomers(ctx context.Context) (bool, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return len(r.store.customers.visible(t)) > 0, nil
+}
+
+func (r *CustomerRepository) GetCustomerByIDForUpdate(ctx context.Context, customerID uuid.UUID) (*models.Customer, error) {
+	var customer *models.Customer
+	err := r.store.exec(ctx, func(t *Tx) error {
+		err := r.store.lock(ctx, t, tableCustomers, customerID)
+		if err != nil {
+			return err
+		}
+		r.store.mu.RLock()
+		defer r.store.mu.RUnlock()
+
+		row, ok := r.store.customers.get(t, customerID)
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		customer = &models.Customer{CustomerID: row.CustomerID, Capital: row.Capital, Ruleset: row.Ruleset, Version: row.Version}
+		return nil
+	})
+	return customer, err
+}
+
+func (r *CustomerRepository) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		err := r.store.lock(ctx, t, tableCustomers, customer.CustomerID)
+		if err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		row, ok := r.store.customers.get(t, customer.CustomerID)
+		if !ok || row.Version != customer.Version {
+			return models.ErrVersionMismatch
+		}
+		row.Capital = customer.Capital
+		row.Version++
+		r.store.customers.put(t, customer.CustomerID, row)
+		customer.Version = row.Version
+		return nil
+	})
+}
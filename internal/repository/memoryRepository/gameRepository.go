@@ -0,0 +1,62 @@
+package memoryRepository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type GameRepository struct {
+	store *Store
+}
+
+func NewGameRepository(store *Store) *GameRepository {
+	return &GameRepository{store: store}
+}
+
+func (r *GameRepository) GetOrCreateSeed(ctx context.Context, seed int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.gameSeed == nil {
+		r.store.gameSeed = &seed
+		r.store.gameEpoch = r.store.now()
+	}
+	return *r.store.gameSeed, nil
+}
+
+func (r *GameRepository) GetEpoch(ctx context.Context) (time.Time, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if r.store.gameSeed == nil {
+		return time.Time{}, pgx.ErrNoRows
+	}
+	return r.store.gameEpoch, nil
+}
+
+func (r *GameRepository) NextRegistration(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.store.exec(ctx, func(t *Tx) error {
+		// Игра одна, ее строка - нулевой идентификатор
+		err := r.store.lock(ctx, t, tableGames, uuid.Nil)
+		if err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		if r.store.gameSeed == nil {
+			return pgx.ErrNoRows
+		}
+		old := r.store.registrations
+		t.undo = append(t.undo, func() {
+			r.store.registrations = old
+		})
+		r.store.registrations++
+		n = r.store.registrations
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,171 @@
+package memoryRepository
+
+import (
+	"context"
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/lockorder"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"sort"
+)
+
+type LoaderRepository struct {
+	store *Store
+}
+
+func NewLoaderRepository(store *Store) *LoaderRepository {
+	return &LoaderRepository{store: store}
+}
+
+// loaderSorts повторяет порядок сортировки loaderRepository; равные значения упорядочиваются по loader_id.
+var loaderSorts = map[string]func(a, b *models.Loader) bool{
+	"": func(a, b *models.Loader) bool { return false },
+	models.LoaderSortRating: func(a, b *models.Loader) bool {
+		if a.RatingAvg != b.RatingAvg {
+			return a.RatingAvg > b.RatingAvg
+		}
+		return a.RatingCount > b.RatingCount
+	},
+	models.LoaderSortSalary:    func(a, b *models.Loader) bool { return a.Salary < b.Salary },
+	models.LoaderSortMaxWeight: func(a, b *models.Loader) bool { return a.MaxWeight > b.MaxWeight },
+}
+
+func (r *LoaderRepository) CreateLoader(ctx context.Context, loader *models.Loader) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		if _, ok := r.store.loaders.rows[loader.LoaderID]; ok {
+			return uniqueViolation("loaders_pkey")
+		}
+		row := *loader
+		row.RatingAvg, row.RatingCount = 0, 0
+		row.Version = 1
+		r.store.loaders.put(t, loader.LoaderID, row)
+		return nil
+	})
+}
+
+func (r *LoaderRepository) GetLoaderByID(ctx context.Context, loaderID uuid.UUID) (*models.Loader, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	loader, ok := r.store.loaders.get(t, loaderID)
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	loader.RatingAvg, loader.RatingCount = r.store.rating(t, loaderID)
+	return &loader, nil
+}
+
+func (r *LoaderRepository) GetLoaders(ctx context.Context) ([]models.Loader, error) {
+	return r.FindLoaders(ctx, &models.LoaderFilter{})
+}
+
+func (r *LoaderRepository) FindLoaders(ctx context.Context, filter *models.LoaderFilter) ([]models.Loader, error) {
+	less, ok := loaderSorts[filter.Sort]
+	if !ok {
+		return nil, errors.New("unknown sort")
+	}
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var loaders []models.Loader
+	for _, loader := range r.store.loaders.visible(t) {
+		loader.RatingAvg, loader.RatingCount = r.store.rating(t, loader.LoaderID)
+		if filter.MinRating != nil && loader.RatingAvg < *filter.MinRating {
+			continue
+		}
+		loaders = append(loaders, loader)
+	}
+	sort.Slice(loaders, func(i, j int) bool {
+		if less(&loaders[i], &loaders[j]) {
+			return true
+		}
+		if less(&loaders[j], &loaders[i]) {
+			return false
+		}
+		return lessID(loaders[i].LoaderID, loaders[j].LoaderID)
+	})
+	return loaders, nil
+}
+
+func (r *LoaderRepository) GetDemand(ctx context.Context) (float64, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	loaders := r.store.loaders.visible(t)
+	if len(loaders) == 0 {
+		return 0, nil
+	}
+	var busy int
+	for _, contract := range r.store.contracts.visible(t) {
+		if contract.Status == models.ContractActive {
+			busy++
+		}
+	}
+	return float64(busy) / float64(len(loaders)), nil
+}
+
+func (r *LoaderRepository) GetLoadersByIDsForUpdate(ctx context.Context, loaderIDs []uuid.UUID) ([]models.Loader, error) {
+	err := r.LockLoaders(ctx, loaderIDs)
+	if err != nil {
+		return nil, err
+	}
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	loaders := make([]models.Loader, 0, len(loaderIDs))
+	seen := make(map[uuid.UUID]struct{}, len(loaderIDs))
+	for _, loaderID := range loaderIDs {
+		loader, ok := r.store.loaders.get(t, loaderID)
+		if _, dup := seen[loaderID]; !ok || dup {
+			return nil, errors.New("wrong loaders")
+		}
+		seen[loaderID] = struct{}{}
+		loader.RatingAvg, loader.RatingCount = 0, 0
+		loaders = append(loaders, loader)
+	}
+	sort.Slice(loaders, func(i, j int) bool {
+		return lessID(loaders[i].LoaderID, loaders[j].LoaderID)
+	})
+	return loaders, nil
+}
+
+func (r *LoaderRepository) LockLoaders(ctx context.Context, loaderIDs []uuid.UUID) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		return r.store.lock(ctx, t, tableLoaders, lockorder.Sorted(loaderIDs)...)
+	})
+}
+
+func (r *LoaderRepository) UpdateLoaders(ctx context.Context, loaders []models.Loader) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		for i := range loaders {
+			err := r.store.lock(ctx, t, tableLoaders, loaders[i].LoaderID)
+			if err != nil {
+				return err
+			}
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		for _, loader := range loaders {
+			if row, ok := r.store.loaders.get(t, loader.LoaderID); !ok || row.Version != loader.Version {
+				return models.ErrVersionMismatch
+			}
+		}
+		for i := range loaders {
+			row, _ := r.store.loaders.get(t, loaders[i].LoaderID)
+			row.MaxWeight, row.Drunk, row.Fatigue, row.Salary, row.Experience, row.Level = loaders[i].MaxWeight, loaders[i].Drunk, loaders[i].Fatigue, loaders[i].Salary, loaders[i].Experience, loaders[i].Level
+			row.Version++
+			r.store.loaders.put(t, loaders[i].LoaderID, row)
+			loaders[i].Version = row.Version
+		}
+		return nil
+	})
+}
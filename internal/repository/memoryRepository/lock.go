@@ -0,0 +1,89 @@
+package memoryRepository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	tableCustomers    = "customers"
+	tableLoaders      = "loaders"
+	tableTasks        = "tasks"
+	tableContracts    = "contracts"
+	tableApplications = "applications"
+	tableGames        = "games"
+)
+
+type rowKey struct {
+	table string
+	id    uuid.UUID
+}
+
+// lock блокирует строки таблицы до конца транзакции в переданном порядке. Если строка занята
+// другой транзакцией, lock ждет ее завершения или отмены ctx. Вызывается без s.mu.
+func (s *Store) lock(ctx context.Context, t *Tx, table string, ids ...uuid.UUID) error {
+	root := t.root()
+
+	s.lockMu.Lock()
+	defer s.lockMu.Unlock()
+
+	for _, id := range ids {
+		key := rowKey{table: table, id: id}
+		for {
+			owner, ok := s.owners[key]
+			if !ok {
+				s.owners[key] = root
+				root.locked = append(root.locked, key)
+				break
+			}
+			if owner == root {
+				break
+			}
+			if s.deadlock(root, owner) {
+				return &pgconn.PgError{Code: "40P01", Message: "deadlock detected"}
+			}
+			s.waiting[root] = key
+			released := s.released
+			s.lockMu.Unlock()
+			select {
+			case <-released:
+			case <-ctx.Done():
+			}
+			s.lockMu.Lock()
+			delete(s.waiting, root)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deadlock сообщает, ждет ли owner (прямо или через другие транзакции) блокировку, которую держит root.
+// Вызывается под s.lockMu.
+func (s *Store) deadlock(root, owner *Tx) bool {
+	for i := 0; i <= len(s.waiting); i++ {
+		key, ok := s.waiting[owner]
+		if !ok {
+			return false
+		}
+		owner = s.owners[key]
+		if owner == root {
+			return true
+		}
+	}
+	return false
+}
+
+// release снимает все блокировки транзакции верхнего уровня.
+func (s *Store) release(root *Tx) {
+	s.lockMu.Lock()
+	for _, key := range root.locked {
+		delete(s.owners, key)
+	}
+	root.locked = nil
+	close(s.released)
+	s.released = make(chan struct{})
+	s.lockMu.Unlock()
+}
@@ -0,0 +1,18 @@
+package memoryRepository
+
+import "github.com/AhegaoHD/WBT/internal/repository"
+
+// NewRepositories собирает все репозитории поверх одного хранилища.
+func NewRepositories(store *Store) *repository.Repositories {
+	return &repository.Repositories{
+		Tx:           store,
+		Users:        NewUserRepository(store),
+		Customers:    NewCustomerRepository(store),
+		Loaders:      NewLoaderRepository(store),
+		Tasks:        NewTaskRepository(store),
+		Contracts:    NewContractRepository(store),
+		Applications: NewApplicationRepository(store),
+		Reviews:      NewReviewRepository(store),
+		Games:        NewGameRepository(store),
+	}
+}
@@ -0,0 +1,77 @@
+package memoryRepository
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/internal/repository/reviewRepository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"sort"
+)
+
+type ReviewRepository struct {
+	store *Store
+}
+
+func NewReviewRepository(store *Store) *ReviewRepository {
+	return &ReviewRepository{store: store}
+}
+
+func (r *ReviewRepository) CreateReview(ctx context.Context, review *models.Review) (*models.Review, error) {
+	err := r.store.exec(ctx, func(t *Tx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		for _, other := range r.store.reviews.rows {
+			if other.TaskID == review.TaskID && other.AuthorID == review.AuthorID && other.TargetID == review.TargetID {
+				return reviewRepository.ErrReviewExists
+			}
+		}
+		review.ReviewID = r.store.newID()
+		review.CreatedAt = r.store.now()
+		r.store.reviews.put(t, review.ReviewID, *review)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (r *ReviewRepository) GetTaskParticipants(ctx context.Context, taskID uuid.UUID) (*models.TaskParticipants, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	task, ok := r.store.tasks.get(t, taskID)
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	participants := &models.TaskParticipants{CustomerID: task.CustomerID, Completed: task.Status, LoaderIDs: []uuid.UUID{}}
+	for key := range r.store.taskLoaders.visible(t) {
+		if key.taskID == taskID {
+			participants.LoaderIDs = append(participants.LoaderIDs, key.loaderID)
+		}
+	}
+	sort.Slice(participants.LoaderIDs, func(i, j int) bool {
+		return lessID(participants.LoaderIDs[i], participants.LoaderIDs[j])
+	})
+	return participants, nil
+}
+
+func (r *ReviewRepository) GetReviewsByTarget(ctx context.Context, targetID uuid.UUID) ([]models.Review, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var reviews []models.Review
+	for _, review := range r.store.reviews.visible(t) {
+		if review.TargetID == targetID {
+			reviews = append(reviews, review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool {
+		return lessID(reviews[i].ReviewID, reviews[j].ReviewID)
+	})
+	return reviews, nil
+}
@@ -0,0 +1,194 @@
+package memoryRepository
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/retry"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"sync"
+	"time"
+)
+
+// Store - хранилище в памяти с теми же таблицами, что и в Postgres. Используется
+// симулятором, тестами и для локальной разработки без базы.
+//
+// Изменения пишутся сразу в таблицы, а журнал транзакции позволяет откатить их.
+// Блокировки строк эмулируются: FOR UPDATE и изменение строки ждут, пока ее не
+// освободит другая транзакция, а взаимная блокировка завершается ошибкой 40P01,
+// как в Postgres. Блокировки держатся до конца транзакции. Как и в read committed,
+// чтения видят зафиксированные строки и изменения своей транзакции, а проверки
+// уникальности - и незафиксированные строки других транзакций.
+type Store struct {
+	mu  sync.RWMutex
+	seq uint64
+	now func() time.Time
+	// txRetry - повторы транзакций верхнего уровня после deadlock, по умолчанию выключены.
+	txRetry retry.Policy
+
+	lockMu sync.Mutex
+	// released закрывается и заменяется новым каналом при каждом снятии блокировок
+	released chan struct{}
+	owners   map[rowKey]*Tx
+	waiting  map[*Tx]rowKey
+
+	users       *table[uuid.UUID, models.User]
+	customers   *table[uuid.UUID, models.Customer]
+	loaders     *table[uuid.UUID, models.Loader]
+	tasks       *table[uuid.UUID, models.Task]
+	taskLoaders *table[taskLoaderKey, models.TaskLoader]
+	damages     *table[uuid.UUID, models.DamageEvent]
+	contracts   *table[uuid.UUID, models.Contract]
+	apps        *table[uuid.UUID, models.Application]
+	reviews     *table[uuid.UUID, models.Review]
+	gameSeed    *int64
+	// gameEpoch - время создания игры, как games.created_at
+	gameEpoch time.Time
+	// registrations - счетчик регистраций игры, как games.registrations
+	registrations int64
+}
+
+type taskLoaderKey struct {
+	taskID   uuid.UUID
+	loaderID uuid.UUID
+}
+
+func NewStore() *Store {
+	s := &Store{
+		now:         time.Now,
+		released:    make(chan struct{}),
+		owners:      make(map[rowKey]*Tx),
+		waiting:     make(map[*Tx]rowKey),
+		users:       newTable[uuid.UUID, models.User](),
+		customers:   newTable[uuid.UUID, models.Customer](),
+		loaders:     newTable[uuid.UUID, models.Loader](),
+		tasks:       newTable[uuid.UUID, models.Task](),
+		taskLoaders: newTable[taskLoaderKey, models.TaskLoader](),
+		damages:     newTable[uuid.UUID, models.DamageEvent](),
+		contracts:   newTable[uuid.UUID, models.Contract](),
+		apps:        newTable[uuid.UUID, models.Application](),
+		reviews:     newTable[uuid.UUID, models.Review](),
+	}
+	return s
+}
+
+// SetTxRetry включает повтор транзакций WithinTx, прерванных из-за deadlock, как в Postgres.
+func (s *Store) SetTxRetry(policy retry.Policy) {
+	s.txRetry = policy
+}
+
+// newID возвращает следующий по порядку идентификатор. Идентификаторы растут в порядке
+// создания строк, поэтому сортировка по ним заменяет сортировку по created_at, а
+// повторный прогон с тем же seed дает те же идентификаторы. Вызывается под s.mu.
+func (s *Store) newID() uuid.UUID {
+	s.seq++
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[8:], s.seq)
+	return id
+}
+
+// table - таблица Store. rows содержит последнюю версию строк, в том числе
+// незафиксированную, а dirty - зафиксированную версию строк, которые изменила
+// еще не завершенная транзакция.
+type table[K comparable, V any] struct {
+	rows  map[K]V
+	dirty map[K]dirtyRow[V]
+}
+
+// dirtyRow - зафиксированная версия строки и транзакция верхнего уровня, которая ее изменила.
+// ok == false, если строка вставлена этой транзакцией.
+type dirtyRow[V any] struct {
+	owner *Tx
+	row   V
+	ok    bool
+}
+
+func newTable[K comparable, V any]() *table[K, V] {
+	return &table[K, V]{rows: make(map[K]V), dirty: make(map[K]dirtyRow[V])}
+}
+
+// get возвращает строку в том виде, в каком ее видит транзакция t (nil - вне транзакции).
+// Вызывается под s.mu.
+func (tb *table[K, V]) get(t *Tx, key K) (V, bool) {
+	if d, ok := tb.dirty[key]; ok && d.owner != t.root() {
+		return d.row, d.ok
+	}
+	row, ok := tb.rows[key]
+	return row, ok
+}
+
+// visible возвращает строки, которые видит транзакция t (nil - вне транзакции). Результат
+// нельзя изменять. Вызывается под s.mu.
+func (tb *table[K, V]) visible(t *Tx) map[K]V {
+	root := t.root()
+	foreign := false
+	for _, d := range tb.dirty {
+		if d.owner != root {
+			foreign = true
+			break
+		}
+	}
+	if !foreign {
+		return tb.rows
+	}
+	rows := make(map[K]V, len(tb.rows))
+	for key, row := range tb.rows {
+		rows[key] = row
+	}
+	for key, d := range tb.dirty {
+		switch {
+		case d.owner == root:
+		case d.ok:
+			rows[key] = d.row
+		default:
+			delete(rows, key)
+		}
+	}
+	return rows
+}
+
+// put записывает строку в таблицу и запоминает в журнале tx, как ее восстановить. До конца
+// транзакции верхнего уровня другие транзакции видят прежнюю версию строки. Вызывается под s.mu.
+func (tb *table[K, V]) put(tx *Tx, key K, row V) {
+	old, ok := tb.rows[key]
+	tx.undo = append(tx.undo, func() {
+		if ok {
+			tb.rows[key] = old
+		} else {
+			delete(tb.rows, key)
+		}
+	})
+	if _, dirty := tb.dirty[key]; !dirty {
+		root := tx.root()
+		tb.dirty[key] = dirtyRow[V]{owner: root, row: old, ok: ok}
+		root.written = append(root.written, func() {
+			delete(tb.dirty, key)
+		})
+	}
+	tb.rows[key] = row
+}
+
+func lessID(a, b uuid.UUID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// rating возвращает среднюю оценку и число отзывов о пользователе, которые видит t. Вызывается под s.mu.
+func (s *Store) rating(t *Tx, targetID uuid.UUID) (float64, int) {
+	var sum, count int
+	for _, review := range s.reviews.visible(t) {
+		if review.TargetID == targetID {
+			sum += review.Rating
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return float64(sum) / float64(count), count
+}
+
+// uniqueViolation - та же ошибка, что возвращает Postgres при нарушении уникального индекса.
+func uniqueViolation(constraint string) error {
+	return &pgconn.PgError{Code: "23505", Message: "duplicate key value violates unique constraint", ConstraintName: constraint}
+}
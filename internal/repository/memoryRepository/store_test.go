@@ -0,0 +1,388 @@
+package memoryRepository
+
+import (
+	"context"
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/retry"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"testing"
+	"time"
+)
+
+var errAbort = errors.New("abort")
+
+func createLoader(t *testing.T, repo *LoaderRepository, fatigue int) uuid.UUID {
+	t.Helper()
+	loader := &models.Loader{LoaderID: uuid.New(), MaxWeight: 10, Fatigue: fatigue}
+	err := repo.CreateLoader(context.Background(), loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loader.LoaderID
+}
+
+func fatigue(t *testing.T, repo *LoaderRepository, loaderID uuid.UUID) int {
+	t.Helper()
+	loader, err := repo.GetLoaderByID(context.Background(), loaderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loader.Fatigue
+}
+
+func TestRollbackRestoresRows(t *testing.T) {
+	store := NewStore()
+	repo := NewLoaderRepository(store)
+	loaderID := createLoader(t, repo, 10)
+
+	err := store.WithinTx(context.Background(), func(ctx context.Context) error {
+		err := repo.UpdateLoaders(ctx, []models.Loader{{LoaderID: loaderID, Version: 1, MaxWeight: 10, Fatigue: 90}})
+		if err != nil {
+			return err
+		}
+		err = repo.CreateLoader(ctx, &models.Loader{LoaderID: uuid.New()})
+		if err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("err = %v, want %v", err, errAbort)
+	}
+
+	if f := fatigue(t, repo, loaderID); f != 10 {
+		t.Errorf("fatigue = %d, want 10", f)
+	}
+	loaders, _ := repo.GetLoaders(context.Background())
+	if len(loaders) != 1 {
+		t.Errorf("%d loaders, want 1", len(loaders))
+	}
+}
+
+func TestNestedTxRollbackKeepsOuterChanges(t *testing.T) {
+	store := NewStore()
+	repo := NewLoaderRepository(store)
+	first := createLoader(t, repo, 0)
+	second := createLoader(t, repo, 0)
+
+	err := store.WithinTx(context.Background(), func(ctx context.Context) error {
+		err := repo.UpdateLoaders(ctx, []models.Loader{{LoaderID: first, Version: 1, Fatigue: 20}})
+		if err != nil {
+			return err
+		}
+		err = store.WithinTx(ctx, func(ctx context.Context) error {
+			err := repo.UpdateLoaders(ctx, []models.Loader{{LoaderID: second, Version: 1, Fatigue: 20}})
+			if err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if err != errAbort {
+			t.Errorf("nested err = %v, want %v", err, errAbort)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f := fatigue(t, repo, first); f != 20 {
+		t.Errorf("first fatigue = %d, want 20", f)
+	}
+	if f := fatigue(t, repo, second); f != 0 {
+		t.Errorf("second fatigue = %d, want 0", f)
+	}
+}
+
+func TestPanicRollsBack(t *testing.T) {
+	store := NewStore()
+	repo := NewLoaderRepository(store)
+	loaderID := createLoader(t, repo, 10)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic was not propagated")
+			}
+		}()
+		_ = store.WithinTx(context.Background(), func(ctx context.Context) error {
+			err := repo.UpdateLoaders(ctx, []models.Loader{{LoaderID: loaderID, Version: 1, Fatigue: 90}})
+			if err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+
+	if f := fatigue(t, repo, loaderID); f != 10 {
+		t.Errorf("fatigue = %d, want 10", f)
+	}
+	// Блокировка снята вместе с откатом
+	err := repo.LockLoaders(context.Background(), []uuid.UUID{loaderID})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockWaitsForCommit(t *testing.T) {
+	store := NewStore()
+	repo := NewLoaderRepository(store)
+	loaderID := createLoader(t, repo, 0)
+
+	locked := make(chan struct{})
+	commit := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- store.WithinTx(context.Background(), func(ctx context.Context) error {
+			err := repo.LockLoaders(ctx, []uuid.UUID{loaderID})
+			close(locked)
+			<-commit
+			return err
+		})
+	}()
+	<-locked
+
+	second := make(chan error)
+	go func() {
+		second <- repo.LockLoaders(context.Background(), []uuid.UUID{loaderID})
+	}()
+
+	select {
+	case <-second:
+		t.Fatal("second transaction got the lock before the first one finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(commit)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-second; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockWaitStopsOnCancel(t *testing.T) {
+	store := NewStore()
+	repo := NewLoaderRepository(store)
+	loaderID := createLoader(t, repo, 0)
+
+	locked := make(chan struct{})
+	commit := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- store.WithinTx(context.Background(), func(ctx context.Context) error {
+			err := repo.LockLoaders(ctx, []uuid.UUID{loaderID})
+			close(locked)
+			<-commit
+			return err
+		})
+	}()
+	<-locked
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := repo.LockLoaders(ctx, []uuid.UUID{loaderID})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(commit)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// Отмененное ожидание не должно оставить следов в графе ожиданий
+	err = repo.LockLoaders(context.Background(), []uuid.UUID{loaderID})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUncommittedChangesAreInvisible(t *testing.T) {
+	store := NewStore()
+	repo := NewLoaderRepository(store)
+	loaderID := createLoader(t, repo, 10)
+
+	updated := make(chan struct{})
+	commit := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- store.WithinTx(context.Background(), func(ctx context.Context) error {
+			err := repo.UpdateLoaders(ctx, []models.Loader{{LoaderID: loaderID, Version: 1, MaxWeight: 10, Fatigue: 90}})
+			if err != nil {
+				return err
+			}
+			err = repo.CreateLoader(ctx, &models.Loader{LoaderID: uuid.New()})
+			if err != nil {
+				return err
+			}
+			if f := fatigue(t, repo, loaderID); f != 10 {
+				t.Errorf("fatigue outside the transaction = %d, want 10", f)
+			}
+			own, err := repo.GetLoaderByID(ctx, loaderID)
+			if err != nil {
+				return err
+			}
+			if own.Fatigue != 90 {
+				t.Errorf("fatigue inside the transaction = %d, want 90", own.Fatigue)
+			}
+			close(updated)
+			<-commit
+			return nil
+		})
+	}()
+	<-updated
+
+	loaders, _ := repo.GetLoaders(context.Background())
+	if len(loaders) != 1 {
+		t.Errorf("%d loaders before commit, want 1", len(loaders))
+	}
+	close(commit)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if f := fatigue(t, repo, loaderID); f != 90 {
+		t.Errorf("fatigue after commit = %d, want 90", f)
+	}
+	loaders, _ = repo.GetLoaders(context.Background())
+	if len(loaders) != 2 {
+		t.Errorf("%d loaders after commit, want 2", len(loaders))
+	}
+}
+
+func TestLockDetectsDeadlock(t *testing.T) {
+	store := NewStore()
+	repo := NewLoaderRepository(store)
+	a := createLoader(t, repo, 0)
+	b := createLoader(t, repo, 0)
+
+	lockedA := make(chan struct{})
+	first := make(chan error)
+	go func() {
+		first <- store.WithinTx(context.Background(), func(ctx context.Context) error {
+			err := repo.LockLoaders(ctx, []uuid.UUID{a})
+			if err != nil {
+				return err
+			}
+			close(lockedA)
+			return repo.LockLoaders(ctx, []uuid.UUID{b})
+		})
+	}()
+
+	err := store.WithinTx(context.Background(), func(ctx context.Context) error {
+		err := repo.LockLoaders(ctx, []uuid.UUID{b})
+		if err != nil {
+			return err
+		}
+		<-lockedA
+		// Ждем, пока первая транзакция встанет в очередь за b
+		for {
+			store.lockMu.Lock()
+			n := len(store.waiting)
+			store.lockMu.Unlock()
+			if n == 1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		return repo.LockLoaders(ctx, []uuid.UUID{a})
+	})
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "40P01" {
+		t.Fatalf("err = %v, want deadlock", err)
+	}
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeadlockVictimIsRetried(t *testing.T) {
+	store := NewStore()
+	store.SetTxRetry(retry.Policy{Retries: 1, Delay: time.Millisecond})
+	repo := NewLoaderRepository(store)
+	a := createLoader(t, repo, 0)
+	b := createLoader(t, repo, 0)
+
+	lockedA := make(chan struct{})
+	firstDone := make(chan struct{})
+	var firstErr error
+	go func() {
+		defer close(firstDone)
+		firstErr = store.WithinTx(context.Background(), func(ctx context.Context) error {
+			err := repo.LockLoaders(ctx, []uuid.UUID{a})
+			if err != nil {
+				return err
+			}
+			close(lockedA)
+			return repo.LockLoaders(ctx, []uuid.UUID{b})
+		})
+	}()
+
+	attempts := 0
+	err := store.WithinTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts > 1 {
+			// Повтор начинается после того, как первая транзакция дождалась b и завершилась
+			<-firstDone
+		}
+		err := repo.LockLoaders(ctx, []uuid.UUID{b})
+		if err != nil {
+			return err
+		}
+		if attempts == 1 {
+			<-lockedA
+			for {
+				store.lockMu.Lock()
+				n := len(store.waiting)
+				store.lockMu.Unlock()
+				if n == 1 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		return repo.LockLoaders(ctx, []uuid.UUID{a})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	<-firstDone
+	if firstErr != nil {
+		t.Fatal(firstErr)
+	}
+}
+
+func TestUpdateRejectsStaleVersion(t *testing.T) {
+	store := NewStore()
+	repo := NewLoaderRepository(store)
+	loaderID := createLoader(t, repo, 0)
+
+	loader, err := repo.GetLoaderByID(context.Background(), loaderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := *loader
+
+	loaders := []models.Loader{*loader}
+	loaders[0].Fatigue = 20
+	err = repo.UpdateLoaders(context.Background(), loaders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaders[0].Version != loader.Version+1 {
+		t.Errorf("version = %d, want %d", loaders[0].Version, loader.Version+1)
+	}
+
+	stale.Fatigue = 50
+	err = repo.UpdateLoaders(context.Background(), []models.Loader{stale})
+	if !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("err = %v, want %v", err, models.ErrVersionMismatch)
+	}
+	if f := fatigue(t, repo, loaderID); f != 20 {
+		t.Errorf("fatigue = %d, want 20", f)
+	}
+}
@@ -0,0 +1,224 @@
+package memoryRepository
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/internal/lockorder"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"sort"
+)
+
+type TaskRepository struct {
+	store *Store
+}
+
+func NewTaskRepository(store *Store) *TaskRepository {
+	return &TaskRepository{store: store}
+}
+
+func (r *TaskRepository) CreateTasks(ctx context.Context, tasks []models.Task) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		for _, task := range tasks {
+			task.TaskID = r.store.newID()
+			task.DamagePenalty = 0
+			task.Published = false
+			task.Version = 1
+			r.store.tasks.put(t, task.TaskID, task)
+		}
+		return nil
+	})
+}
+
+func (r *TaskRepository) GetTasksCustomers(ctx context.Context, customerID uuid.UUID) ([]models.Task, error) {
+	return r.selectTasks(r.store.txFrom(ctx), func(task *models.Task) bool {
+		return task.CustomerID == customerID && !task.Status
+	}, lessTaskID), nil
+}
+
+func (r *TaskRepository) GetTaskQueue(ctx context.Context, customerID uuid.UUID) ([]models.Task, error) {
+	return r.selectTasks(r.store.txFrom(ctx), func(task *models.Task) bool {
+		return task.CustomerID == customerID && !task.Status
+	}, lessQueue), nil
+}
+
+func (r *TaskRepository) GetPublishedTasks(ctx context.Context) ([]models.Task, error) {
+	return r.selectTasks(r.store.txFrom(ctx), func(task *models.Task) bool {
+		return task.Published && !task.Status
+	}, lessQueue), nil
+}
+
+func (r *TaskRepository) GetTasksLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Task, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	assigned := make(map[uuid.UUID]bool)
+	for key := range r.store.taskLoaders.visible(t) {
+		if key.loaderID == loaderID {
+			assigned[key.taskID] = true
+		}
+	}
+	r.store.mu.RUnlock()
+
+	return r.selectTasks(t, func(task *models.Task) bool {
+		return assigned[task.TaskID]
+	}, lessTaskID), nil
+}
+
+func (r *TaskRepository) GetTaskByID(ctx context.Context, taskID uuid.UUID) (*models.Task, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	task, ok := r.store.tasks.get(t, taskID)
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &task, nil
+}
+
+func (r *TaskRepository) GetTaskByIDForUpdate(ctx context.Context, taskID uuid.UUID) (*models.Task, error) {
+	err := r.store.exec(ctx, func(t *Tx) error {
+		return r.store.lock(ctx, t, tableTasks, taskID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	task, ok := r.store.tasks.get(t, taskID)
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &task, nil
+}
+
+func (r *TaskRepository) LockTasks(ctx context.Context, taskIDs []uuid.UUID) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		return r.store.lock(ctx, t, tableTasks, lockorder.Sorted(taskIDs)...)
+	})
+}
+
+func (r *TaskRepository) UpdateTask(ctx context.Context, task *models.Task) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		err := r.store.lock(ctx, t, tableTasks, task.TaskID)
+		if err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		if row, ok := r.store.tasks.get(t, task.TaskID); !ok || row.Version != task.Version {
+			return models.ErrVersionMismatch
+		}
+		row := *task
+		row.Version++
+		r.store.tasks.put(t, task.TaskID, row)
+		task.Version = row.Version
+		return nil
+	})
+}
+
+func (r *TaskRepository) CreateTaskLoaders(ctx context.Context, taskLoaders []models.TaskLoader) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		// Как и ON CONFLICT ... WHERE price = 0: цена остается зафиксированной с первого
+		// назначения с ненулевой ценой
+		for _, taskLoader := range taskLoaders {
+			key := taskLoaderKey{taskID: taskLoader.TaskID, loaderID: taskLoader.LoaderID}
+			if row, ok := r.store.taskLoaders.rows[key]; ok && row.Price != 0 {
+				continue
+			}
+			r.store.taskLoaders.put(t, key, taskLoader)
+		}
+		return nil
+	})
+}
+
+func (r *TaskRepository) GetTaskLoaderPrices(ctx context.Context, taskID uuid.UUID) (map[uuid.UUID]int, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	prices := make(map[uuid.UUID]int)
+	for key, taskLoader := range r.store.taskLoaders.visible(t) {
+		if key.taskID == taskID && taskLoader.Price > 0 {
+			prices[key.loaderID] = taskLoader.Price
+		}
+	}
+	return prices, nil
+}
+
+func (r *TaskRepository) CreateDamageEvents(ctx context.Context, damages []models.DamageEvent) error {
+	return r.store.exec(ctx, func(t *Tx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		for _, damage := range damages {
+			damage.DamageID = r.store.newID()
+			damage.CreatedAt = r.store.now()
+			r.store.damages.put(t, damage.DamageID, damage)
+		}
+		return nil
+	})
+}
+
+func (r *TaskRepository) GetDamagesCustomers(ctx context.Context, customerID uuid.UUID) ([]models.DamageEvent, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tasks := r.store.tasks.visible(t)
+	var damages []models.DamageEvent
+	for _, damage := range r.store.damages.visible(t) {
+		if tasks[damage.TaskID].CustomerID == customerID {
+			damages = append(damages, damage)
+		}
+	}
+	sort.Slice(damages, func(i, j int) bool {
+		return lessID(damages[i].DamageID, damages[j].DamageID)
+	})
+	return damages, nil
+}
+
+func (r *TaskRepository) selectTasks(t *Tx, match func(task *models.Task) bool, less func(a, b *models.Task) bool) []models.Task {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var tasks []models.Task
+	for _, task := range r.store.tasks.visible(t) {
+		if match(&task) {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return less(&tasks[i], &tasks[j])
+	})
+	return tasks
+}
+
+func lessTaskID(a, b *models.Task) bool {
+	return lessID(a.TaskID, b.TaskID)
+}
+
+// lessQueue - порядок очереди задач: priority DESC, deadline ASC NULLS LAST, task_id.
+func lessQueue(a, b *models.Task) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	switch {
+	case a.Deadline != nil && b.Deadline == nil:
+		return true
+	case a.Deadline == nil && b.Deadline != nil:
+		return false
+	case a.Deadline != nil && !a.Deadline.Equal(*b.Deadline):
+		return a.Deadline.Before(*b.Deadline)
+	}
+	return lessTaskID(a, b)
+}
@@ -0,0 +1,128 @@
+package memoryRepository
+
+import (
+	"context"
+	"errors"
+)
+
+var errTxClosed = errors.New("memoryRepository: transaction is closed")
+
+// Tx - транзакция Store: журнал отмены изменений и блокировки строк.
+type Tx struct {
+	store  *Store
+	parent *Tx
+	undo   []func()
+	locked []rowKey
+	// written снимает отметки с измененных строк, когда транзакция верхнего уровня завершается
+	written []func()
+	done    bool
+}
+
+type txKey struct{}
+
+// WithinTx выполняет fn в транзакции, переданной fn через ctx. Если ctx уже содержит
+// транзакцию Store, fn выполняется в точке сохранения внутри нее. Ошибка или паника
+// в fn откатывает все изменения fn. Транзакция верхнего уровня, прерванная из-за
+// deadlock, повторяется по политике SetTxRetry.
+func (s *Store) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	parent := s.txFrom(ctx)
+	if parent != nil {
+		return s.runTx(ctx, parent, fn)
+	}
+	return s.txRetry.Do(ctx, func() error {
+		return s.runTx(ctx, nil, fn)
+	})
+}
+
+func (s *Store) runTx(ctx context.Context, parent *Tx, fn func(ctx context.Context) error) error {
+	t := &Tx{store: s, parent: parent}
+	return t.run(func() error {
+		return fn(context.WithValue(ctx, txKey{}, t))
+	})
+}
+
+// exec выполняет fn в транзакции из ctx, а вне транзакции - в отдельной транзакции,
+// как одиночный запрос в Postgres.
+func (s *Store) exec(ctx context.Context, fn func(t *Tx) error) error {
+	if t := s.txFrom(ctx); t != nil {
+		if t.done {
+			return errTxClosed
+		}
+		return fn(t)
+	}
+	t := &Tx{store: s}
+	return t.run(func() error {
+		return fn(t)
+	})
+}
+
+// txFrom возвращает транзакцию Store из ctx или nil.
+func (s *Store) txFrom(ctx context.Context) *Tx {
+	t, ok := ctx.Value(txKey{}).(*Tx)
+	if !ok || t.store != s {
+		return nil
+	}
+	return t
+}
+
+func (t *Tx) run(fn func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			t.rollback()
+			panic(p)
+		}
+	}()
+	err = fn()
+	if err != nil {
+		t.rollback()
+		return err
+	}
+	t.commit()
+	return nil
+}
+
+// root возвращает транзакцию верхнего уровня, для nil - nil.
+func (t *Tx) root() *Tx {
+	for t != nil && t.parent != nil {
+		t = t.parent
+	}
+	return t
+}
+
+func (t *Tx) commit() {
+	t.done = true
+	if t.parent != nil {
+		t.parent.undo = append(t.parent.undo, t.undo...)
+		return
+	}
+	t.store.mu.Lock()
+	t.forget()
+	t.store.mu.Unlock()
+	t.store.release(t)
+}
+
+func (t *Tx) rollback() {
+	t.done = true
+
+	t.store.mu.Lock()
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		t.undo[i]()
+	}
+	if t.parent == nil {
+		t.forget()
+	}
+	t.store.mu.Unlock()
+	t.undo = nil
+
+	if t.parent == nil {
+		t.store.release(t)
+	}
+}
+
+// forget делает изменения транзакции верхнего уровня видимыми всем. Вызывается под s.mu.
+func (t *Tx) forget() {
+	for _, fn := range t.written {
+		fn()
+	}
+	t.written = nil
+}
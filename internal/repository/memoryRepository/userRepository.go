@@ -0,0 +1,63 @@
+package memoryRepository
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+type UserRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) *UserRepository {
+	return &UserRepository{store: store}
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	err := r.store.exec(ctx, func(t *Tx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+
+		for _, existing := range r.store.users.rows {
+			if existing.Username == user.Username {
+				return uniqueViolation("users_username_key")
+			}
+		}
+		user.UserID = r.store.newID()
+		row := *user
+		row.Ruleset = ""
+		r.store.users.put(t, user.UserID, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *UserRepository) UsernameExists(ctx context.Context, username string) (bool, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users.visible(t) {
+		if user.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	t := r.store.txFrom(ctx)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users.visible(t) {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Repositories - все репозитории одного бэкенда хранения (Postgres или память)
+// и источник транзакций для сервисов.
+type Repositories struct {
+	Tx           TxManager
+	Users        UserRepository
+	Customers    CustomerRepository
+	Loaders      LoaderRepository
+	Tasks        TaskRepository
+	Contracts    ContractRepository
+	Applications ApplicationRepository
+	Reviews      ReviewRepository
+	Games        GameRepository
+}
+
+// TxManager выполняет fn в транзакции, которую репозитории берут из переданного fn контекста.
+// Вызов внутри другой транзакции открывает точку сохранения: ошибка fn откатывает только ее.
+// Вне транзакции каждый метод репозитория выполняется сам по себе.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, user *models.User) (*models.User, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+}
+
+type CustomerRepository interface {
+	CreateCustomer(ctx context.Context, customer *models.Customer) error
+	GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error)
+	HasCustomers(ctx context.Context) (bool, error)
+	GetCustomerByIDForUpdate(ctx context.Context, customerID uuid.UUID) (*models.Customer, error)
+	UpdateCustomer(ctx context.Context, customer *models.Customer) error
+}
+
+type LoaderRepository interface {
+	CreateLoader(ctx context.Context, loader *models.Loader) error
+	GetLoaderByID(ctx context.Context, loaderID uuid.UUID) (*models.Loader, error)
+	GetLoaders(ctx context.Context) ([]models.Loader, error)
+	FindLoaders(ctx context.Context, filter *models.LoaderFilter) ([]models.Loader, error)
+	GetDemand(ctx context.Context) (float64, error)
+	GetLoadersByIDsForUpdate(ctx context.Context, loaderIDs []uuid.UUID) ([]models.Loader, error)
+	LockLoaders(ctx context.Context, loaderIDs []uuid.UUID) error
+	UpdateLoaders(ctx context.Context, loaders []models.Loader) error
+}
+
+type TaskRepository interface {
+	CreateTasks(ctx context.Context, tasks []models.Task) error
+	GetTasksCustomers(ctx context.Context, customerID uuid.UUID) ([]models.Task, error)
+	GetTaskQueue(ctx context.Context, customerID uuid.UUID) ([]models.Task, error)
+	GetPublishedTasks(ctx context.Context) ([]models.Task, error)
+	GetTasksLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Task, error)
+	GetTaskByID(ctx context.Context, taskID uuid.UUID) (*models.Task, error)
+	GetTaskByIDForUpdate(ctx context.Context, taskID uuid.UUID) (*models.Task, error)
+	LockTasks(ctx context.Context, taskIDs []uuid.UUID) error
+	UpdateTask(ctx context.Context, task *models.Task) error
+	CreateTaskLoaders(ctx context.Context, taskLoaders []models.TaskLoader) error
+	GetTaskLoaderPrices(ctx context.Context, taskID uuid.UUID) (map[uuid.UUID]int, error)
+	CreateDamageEvents(ctx context.Context, damages []models.DamageEvent) error
+	GetDamagesCustomers(ctx context.Context, customerID uuid.UUID) ([]models.DamageEvent, error)
+}
+
+type ContractRepository interface {
+	CreateContract(ctx context.Context, contract *models.Contract) (*models.Contract, error)
+	GetContractByIDForUpdate(ctx context.Context, contractID uuid.UUID) (*models.Contract, error)
+	UpdateContract(ctx context.Context, contract *models.Contract) error
+	GetContractsCustomers(ctx context.Context, customerID uuid.UUID) ([]models.Contract, error)
+	GetContractsLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Contract, error)
+	GetActiveContracts(ctx context.Context, customerID uuid.UUID, loaderIDs []uuid.UUID) ([]models.Contract, error)
+	GetRoster(ctx context.Context, customerID uuid.UUID) ([]models.RosterEntry, error)
+}
+
+type ApplicationRepository interface {
+	CreateApplication(ctx context.Context, application *models.Application) (*models.Application, error)
+	GetApplicationsCustomers(ctx context.Context, customerID uuid.UUID, taskID uuid.UUID) ([]models.Application, error)
+	GetApplicationsLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Application, error)
+	GetApplicationsByIDsForUpdate(ctx context.Context, applicationIDs []uuid.UUID) ([]models.Application, error)
+	CloseApplications(ctx context.Context, taskID uuid.UUID, acceptedIDs []uuid.UUID, rejectRest bool) error
+}
+
+type ReviewRepository interface {
+	CreateReview(ctx context.Context, review *models.Review) (*models.Review, error)
+	GetTaskParticipants(ctx context.Context, taskID uuid.UUID) (*models.TaskParticipants, error)
+	GetReviewsByTarget(ctx context.Context, targetID uuid.UUID) ([]models.Review, error)
+}
+
+type GameRepository interface {
+	GetOrCreateSeed(ctx context.Context, seed int64) (int64, error)
+	GetEpoch(ctx context.Context) (time.Time, error)
+	NextRegistration(ctx context.Context) (int64, error)
+}
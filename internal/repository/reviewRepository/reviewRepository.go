@@ -0,0 +1,85 @@
+package reviewRepository
+
+import (
+	"context"
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolation = "23505"
+
+var ErrReviewExists = errors.New("review for this task already exists")
+
+type ReviewRepository struct {
+	db *postgres.Postgres
+}
+
+func NewReviewRepository(db *postgres.Postgres) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+func (r *ReviewRepository) CreateReview(ctx context.Context, review *models.Review) (*models.Review, error) {
+	const query = `
+		INSERT INTO reviews (task_id, author_id, target_id, rating, comment)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING review_id, created_at`
+
+	err := r.db.Querier(ctx).QueryRow(ctx, query, review.TaskID, review.AuthorID, review.TargetID, review.Rating, review.Comment).Scan(&review.ReviewID, &review.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, ErrReviewExists
+		}
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// GetTaskParticipants возвращает заказчика задачи, признак ее выполнения и всех грузчиков,
+// участвовавших в ее рейсах.
+func (r *ReviewRepository) GetTaskParticipants(ctx context.Context, taskID uuid.UUID) (*models.TaskParticipants, error) {
+	const query = `SELECT t.customer_id, t.status, COALESCE(array_agg(tl.loader_id) FILTER (WHERE tl.loader_id IS NOT NULL), '{}')
+                   FROM tasks t
+                   LEFT JOIN task_loaders tl ON tl.task_id = t.task_id
+                   WHERE t.task_id = $1
+                   GROUP BY t.task_id`
+
+	var participants models.TaskParticipants
+	err := r.db.Querier(ctx).QueryRow(ctx, query, taskID).Scan(&participants.CustomerID, &participants.Completed, &participants.LoaderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &participants, nil
+}
+
+func (r *ReviewRepository) GetReviewsByTarget(ctx context.Context, targetID uuid.UUID) ([]models.Review, error) {
+	const query = `SELECT review_id, task_id, author_id, target_id, rating, comment, created_at
+                   FROM reviews WHERE target_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []models.Review
+	for rows.Next() {
+		var review models.Review
+		err = rows.Scan(&review.ReviewID, &review.TaskID, &review.AuthorID, &review.TargetID, &review.Rating, &review.Comment, &review.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+const taskColumns = `task_id, customer_id, weight, remaining_weight, cargo_type, description, status, priority, deadline, damage_penalty, published, version`
+
 type TaskRepository struct {
 	db *postgres.Postgres
 }
@@ -16,15 +18,15 @@ func NewTaskRepository(db *postgres.Postgres) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
-func (r *TaskRepository) CreateTasks(ctx context.Context, tasks []models.Task, tx pgx.Tx) error {
-	const query = `INSERT INTO tasks (customer_id, weight, description, status) VALUES ($1, $2, $3, $4)`
+func (r *TaskRepository) CreateTasks(ctx context.Context, tasks []models.Task) error {
+	const query = `INSERT INTO tasks (customer_id, weight, remaining_weight, cargo_type, description, status, priority, deadline) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 
 	batch := &pgx.Batch{}
 	for _, task := range tasks {
-		batch.Queue(query, task.CustomerID, task.Weight, task.Description, task.Status)
+		batch.Queue(query, task.CustomerID, task.Weight, task.RemainingWeight, task.CargoType, task.Description, task.Status, task.Priority, task.Deadline)
 	}
 
-	br := tx.SendBatch(ctx, batch)
+	br := r.db.Querier(ctx).SendBatch(ctx, batch)
 	defer br.Close()
 
 	// Проверяем результаты выполнения каждого запроса в пакете
@@ -39,65 +41,76 @@ func (r *TaskRepository) CreateTasks(ctx context.Context, tasks []models.Task, t
 }
 
 func (r *TaskRepository) GetTasksCustomers(ctx context.Context, customerID uuid.UUID) ([]models.Task, error) {
-	const query = `SELECT task_id, customer_id, weight, description, status FROM tasks WHERE customer_id = $1 AND status = '0'`
+	const query = `SELECT ` + taskColumns + ` FROM tasks WHERE customer_id = $1 AND status = '0'`
 
-	rows, err := r.db.Pool.Query(ctx, query, customerID)
+	rows, err := r.db.Querier(ctx).Query(ctx, query, customerID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var tasks []models.Task
-	for rows.Next() {
-		var task models.Task
-		err = rows.Scan(&task.TaskID, &task.CustomerID, &task.Weight, &task.Description, &task.Status)
-		if err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
+	return scanTasks(rows)
+}
+
+// GetTaskQueue возвращает невыполненные задачи заказчика в порядке очереди:
+// сначала по убыванию приоритета, затем по ближайшему дедлайну.
+func (r *TaskRepository) GetTaskQueue(ctx context.Context, customerID uuid.UUID) ([]models.Task, error) {
+	const query = `SELECT ` + taskColumns + ` FROM tasks
+                   WHERE customer_id = $1 AND status = '0'
+                   ORDER BY priority DESC, deadline ASC NULLS LAST, task_id`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, customerID)
+	if err != nil {
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
+	return scanTasks(rows)
+}
+
+// GetPublishedTasks возвращает невыполненные задачи, открытые для откликов грузчиков.
+func (r *TaskRepository) GetPublishedTasks(ctx context.Context) ([]models.Task, error) {
+	const query = `SELECT ` + taskColumns + ` FROM tasks
+                   WHERE published AND status = '0'
+                   ORDER BY priority DESC, deadline ASC NULLS LAST, task_id`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query)
+	if err != nil {
 		return nil, err
 	}
 
-	return tasks, nil
+	return scanTasks(rows)
 }
 
 func (r *TaskRepository) GetTasksLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Task, error) {
-	const query = `SELECT t.task_id, t.customer_id, t.weight, t.description, t.status 
-                   FROM tasks t 
-                   JOIN task_loaders tl ON t.task_id = tl.task_id 
+	const query = `SELECT DISTINCT t.task_id, t.customer_id, t.weight, t.remaining_weight, t.cargo_type, t.description, t.status, t.priority, t.deadline, t.damage_penalty, t.published, t.version
+                   FROM tasks t
+                   JOIN task_loaders tl ON t.task_id = tl.task_id
                    WHERE tl.loader_id = $1`
 
-	rows, err := r.db.Pool.Query(ctx, query, loaderID)
+	rows, err := r.db.Querier(ctx).Query(ctx, query, loaderID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var tasks []models.Task
-	for rows.Next() {
-		var task models.Task
-		err = rows.Scan(&task.TaskID, &task.CustomerID, &task.Weight, &task.Description, &task.Status)
-		if err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
-	}
+	return scanTasks(rows)
+}
 
-	if err = rows.Err(); err != nil {
+func (r *TaskRepository) GetTaskByID(ctx context.Context, taskID uuid.UUID) (*models.Task, error) {
+	const query = `SELECT ` + taskColumns + ` FROM tasks WHERE task_id = $1`
+
+	var task models.Task
+	err := r.db.Querier(ctx).QueryRow(ctx, query, taskID).Scan(&task.TaskID, &task.CustomerID, &task.Weight, &task.RemainingWeight, &task.CargoType, &task.Description, &task.Status, &task.Priority, &task.Deadline, &task.DamagePenalty, &task.Published, &task.Version)
+	if err != nil {
 		return nil, err
 	}
 
-	return tasks, nil
+	return &task, nil
 }
 
-func (r *TaskRepository) GetTaskByIDForUpdate(ctx context.Context, taskID uuid.UUID, tx pgx.Tx) (*models.Task, error) {
-	const query = `SELECT task_id, customer_id, weight, description, status FROM tasks WHERE task_id = $1 FOR UPDATE`
+func (r *TaskRepository) GetTaskByIDForUpdate(ctx context.Context, taskID uuid.UUID) (*models.Task, error) {
+	const query = `SELECT ` + taskColumns + ` FROM tasks WHERE task_id = $1 FOR UPDATE`
 
 	var task models.Task
-	err := tx.QueryRow(ctx, query, taskID).Scan(&task.TaskID, &task.CustomerID, &task.Weight, &task.Description, &task.Status)
+	err := r.db.Querier(ctx).QueryRow(ctx, query, taskID).Scan(&task.TaskID, &task.CustomerID, &task.Weight, &task.RemainingWeight, &task.CargoType, &task.Description, &task.Status, &task.Priority, &task.Deadline, &task.DamagePenalty, &task.Published, &task.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -105,14 +118,33 @@ func (r *TaskRepository) GetTaskByIDForUpdate(ctx context.Context, taskID uuid.U
 	return &task, nil
 }
 
-func (r *TaskRepository) UpdateTask(ctx context.Context, task *models.Task, tx pgx.Tx) error {
-	const query = `UPDATE tasks SET customer_id = $1, weight = $2, description = $3, status = $4 WHERE task_id = $5`
+// LockTasks блокирует строки задач в порядке task_id. Несуществующие идентификаторы пропускаются.
+func (r *TaskRepository) LockTasks(ctx context.Context, taskIDs []uuid.UUID) error {
+	const query = `SELECT task_id FROM tasks WHERE task_id = ANY($1) ORDER BY task_id FOR UPDATE`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, taskIDs)
+	if err != nil {
+		return err
+	}
+	rows.Close()
+
+	return rows.Err()
+}
+
+// UpdateTask сохраняет задачу, если ее версия не изменилась с момента чтения, и увеличивает task.Version.
+func (r *TaskRepository) UpdateTask(ctx context.Context, task *models.Task) error {
+	const query = `UPDATE tasks SET customer_id = $1, weight = $2, remaining_weight = $3, cargo_type = $4, description = $5, status = $6, priority = $7, deadline = $8, damage_penalty = $9, published = $10, version = version + 1
+                   WHERE task_id = $11 AND version = $12`
 
-	_, err := tx.Exec(ctx, query, task.CustomerID, task.Weight, task.Description, task.Status, task.TaskID)
+	tag, err := r.db.Querier(ctx).Exec(ctx, query, task.CustomerID, task.Weight, task.RemainingWeight, task.CargoType, task.Description, task.Status, task.Priority, task.Deadline, task.DamagePenalty, task.Published, task.TaskID, task.Version)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrVersionMismatch
+	}
 
+	task.Version++
 	return nil
 }
 
@@ -124,19 +156,77 @@ func (r *TaskRepository) UpdateTask(ctx context.Context, task *models.Task, tx p
 //	return &TaskLoaderRepository{db: db}
 //}
 
-func (r *TaskRepository) CreateTaskLoaders(ctx context.Context, taskID uuid.UUID, loaderIDs []uuid.UUID, tx pgx.Tx) error {
+func (r *TaskRepository) CreateTaskLoaders(ctx context.Context, taskLoaders []models.TaskLoader) error {
 	batch := &pgx.Batch{}
 
-	const query = `INSERT INTO task_loaders (task_id, loader_id) VALUES ($1, $2)`
-	for _, loaderID := range loaderIDs {
-		batch.Queue(query, taskID, loaderID)
+	// Грузчик может участвовать в нескольких рейсах одной задачи, цена остается
+	// зафиксированной с первого назначения. Нулевая цена (рейс по абонементу или строка
+	// до появления цен) ничего не фиксирует и заменяется первой ненулевой
+	const query = `INSERT INTO task_loaders (task_id, loader_id, price) VALUES ($1, $2, $3)
+		ON CONFLICT (task_id, loader_id) DO UPDATE SET price = EXCLUDED.price WHERE task_loaders.price = 0`
+	for _, taskLoader := range taskLoaders {
+		batch.Queue(query, taskLoader.TaskID, taskLoader.LoaderID, taskLoader.Price)
 	}
 
-	br := tx.SendBatch(ctx, batch)
+	br := r.db.Querier(ctx).SendBatch(ctx, batch)
 	defer br.Close()
 
 	// Проверяем результаты выполнения каждого запроса в пакете
-	for range loaderIDs {
+	for range taskLoaders {
+		_, err := br.Exec()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTaskLoaderPrices возвращает цены, зафиксированные за грузчиками задачи.
+// Строки с нулевой ценой цену не фиксируют и не возвращаются.
+func (r *TaskRepository) GetTaskLoaderPrices(ctx context.Context, taskID uuid.UUID) (map[uuid.UUID]int, error) {
+	const query = `SELECT loader_id, price FROM task_loaders WHERE task_id = $1 AND price > 0`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var loaderID uuid.UUID
+		var price int
+		err = rows.Scan(&loaderID, &price)
+		if err != nil {
+			return nil, err
+		}
+		prices[loaderID] = price
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+func (r *TaskRepository) CreateDamageEvents(ctx context.Context, damages []models.DamageEvent) error {
+	if len(damages) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+
+	const query = `INSERT INTO task_damages (task_id, loader_id, penalty, charged_to) VALUES ($1, $2, $3, $4)`
+	for _, damage := range damages {
+		batch.Queue(query, damage.TaskID, damage.LoaderID, damage.Penalty, damage.ChargedTo)
+	}
+
+	br := r.db.Querier(ctx).SendBatch(ctx, batch)
+	defer br.Close()
+
+	for range damages {
 		_, err := br.Exec()
 		if err != nil {
 			return err
@@ -145,3 +235,53 @@ func (r *TaskRepository) CreateTaskLoaders(ctx context.Context, taskID uuid.UUID
 
 	return nil
 }
+
+func (r *TaskRepository) GetDamagesCustomers(ctx context.Context, customerID uuid.UUID) ([]models.DamageEvent, error) {
+	const query = `SELECT d.damage_id, d.task_id, d.loader_id, d.penalty, d.charged_to, d.created_at
+                   FROM task_damages d
+                   JOIN tasks t ON t.task_id = d.task_id
+                   WHERE t.customer_id = $1
+                   ORDER BY d.created_at`
+
+	rows, err := r.db.Querier(ctx).Query(ctx, query, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var damages []models.DamageEvent
+	for rows.Next() {
+		var damage models.DamageEvent
+		err = rows.Scan(&damage.DamageID, &damage.TaskID, &damage.LoaderID, &damage.Penalty, &damage.ChargedTo, &damage.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		damages = append(damages, damage)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return damages, nil
+}
+
+func scanTasks(rows pgx.Rows) ([]models.Task, error) {
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		var task models.Task
+		err := rows.Scan(&task.TaskID, &task.CustomerID, &task.Weight, &task.RemainingWeight, &task.CargoType, &task.Description, &task.Status, &task.Priority, &task.Deadline, &task.DamagePenalty, &task.Published, &task.Version)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"github.com/AhegaoHD/WBT/internal/models"
 	"github.com/AhegaoHD/WBT/pkg/postgres"
-	"github.com/jackc/pgx/v5"
 )
 
 type UserRepository struct {
@@ -15,13 +14,13 @@ func NewUserRepository(db *postgres.Postgres) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) CreateUser(ctx context.Context, user *models.User, tx pgx.Tx) (*models.User, error) {
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
 	const query = `
 		INSERT INTO users (username, password, user_type)
 		VALUES ($1, $2, $3)
 		RETURNING user_id`
 
-	err := tx.QueryRow(ctx, query, user.Username, user.Password, user.UserType).Scan(&user.UserID)
+	err := r.db.Querier(ctx).QueryRow(ctx, query, user.Username, user.Password, user.UserType).Scan(&user.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -29,11 +28,11 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *models.User, tx p
 	return user, nil
 }
 
-func (r *UserRepository) UsernameExists(ctx context.Context, username string, tx pgx.Tx) (bool, error) {
+func (r *UserRepository) UsernameExists(ctx context.Context, username string) (bool, error) {
 	const query = `SELECT COUNT(*) FROM users WHERE username = $1`
 
 	var count int
-	err := tx.QueryRow(ctx, query, username).Scan(&count)
+	err := r.db.Querier(ctx).QueryRow(ctx, query, username).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -48,7 +47,7 @@ func (r *UserRepository) GetUserByUsername(ctx context.Context, username string)
 		WHERE username = $1`
 
 	var user models.User
-	err := r.db.Pool.QueryRow(ctx, query, username).Scan(&user.UserID, &user.Username, &user.Password, &user.UserType)
+	err := r.db.Querier(ctx).QueryRow(ctx, query, username).Scan(&user.UserID, &user.Username, &user.Password, &user.UserType)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,165 @@
+package contractService
+
+import (
+	"context"
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/google/uuid"
+)
+
+type ContractService struct {
+	tx                 txManager
+	contractRepository contractRepository
+	customerRepository customerRepository
+}
+
+type txManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type contractRepository interface {
+	CreateContract(ctx context.Context, contract *models.Contract) (*models.Contract, error)
+	GetContractByIDForUpdate(ctx context.Context, contractID uuid.UUID) (*models.Contract, error)
+	UpdateContract(ctx context.Context, contract *models.Contract) error
+	GetContractsCustomers(ctx context.Context, customerID uuid.UUID) ([]models.Contract, error)
+	GetContractsLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Contract, error)
+	GetRoster(ctx context.Context, customerID uuid.UUID) ([]models.RosterEntry, error)
+}
+
+type customerRepository interface {
+	GetCustomerByIDForUpdate(ctx context.Context, customerID uuid.UUID) (*models.Customer, error)
+	UpdateCustomer(ctx context.Context, customer *models.Customer) error
+}
+
+func NewContractService(tx txManager, contractRepository contractRepository, customerRepository customerRepository) *ContractService {
+	return &ContractService{tx: tx, contractRepository: contractRepository, customerRepository: customerRepository}
+}
+
+func (s *ContractService) OfferContract(ctx context.Context, req *models.OfferContractRequest) (*models.Contract, error) {
+	if req.User.UserType != "customer" {
+		return nil, errors.New("not customer")
+	}
+
+	return s.contractRepository.CreateContract(ctx, &models.Contract{
+		CustomerID: req.User.UserID,
+		LoaderID:   req.LoaderID,
+		Kind:       req.Kind,
+		Rate:       req.Rate,
+		Status:     models.ContractOffered,
+	})
+}
+
+// AcceptContract делает предложение действующим договором. По договору
+// ContractRetainer заказчик сразу платит грузчику Rate.
+func (s *ContractService) AcceptContract(ctx context.Context, user *models.User, contractID uuid.UUID) error {
+	if user.UserType != "loader" {
+		return errors.New("not loader")
+	}
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		contract, err := s.contractRepository.GetContractByIDForUpdate(ctx, contractID)
+		if err != nil {
+			return err
+		}
+		if contract.LoaderID != user.UserID {
+			return errors.New("contract.LoaderID != user.UserID")
+		}
+		if contract.Status != models.ContractOffered {
+			return errors.New("contract is not offered")
+		}
+
+		if contract.Kind == models.ContractRetainer {
+			customer, err := s.customerRepository.GetCustomerByIDForUpdate(ctx, contract.CustomerID)
+			if err != nil {
+				return err
+			}
+			if customer.Capital < contract.Rate {
+				return errors.New("customer.Capital < contract.Rate")
+			}
+			customer.Capital -= contract.Rate
+			err = s.customerRepository.UpdateCustomer(ctx, customer)
+			if err != nil {
+				return err
+			}
+		}
+
+		contract.Status = models.ContractActive
+		return s.contractRepository.UpdateContract(ctx, contract)
+	})
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).With("contract_id", contractID, "status", models.ContractActive).Info("contract status changed")
+	return nil
+}
+
+func (s *ContractService) DeclineContract(ctx context.Context, user *models.User, contractID uuid.UUID) error {
+	if user.UserType != "loader" {
+		return errors.New("not loader")
+	}
+
+	return s.changeStatus(ctx, contractID, func(contract *models.Contract) error {
+		if contract.LoaderID != user.UserID {
+			return errors.New("contract.LoaderID != user.UserID")
+		}
+		if contract.Status != models.ContractOffered {
+			return errors.New("contract is not offered")
+		}
+		contract.Status = models.ContractDeclined
+		return nil
+	})
+}
+
+// TerminateContract расторгает договор или отзывает предложение. Доступно обеим сторонам.
+func (s *ContractService) TerminateContract(ctx context.Context, user *models.User, contractID uuid.UUID) error {
+	return s.changeStatus(ctx, contractID, func(contract *models.Contract) error {
+		if contract.CustomerID != user.UserID && contract.LoaderID != user.UserID {
+			return errors.New("not a party of the contract")
+		}
+		if contract.Status != models.ContractOffered && contract.Status != models.ContractActive {
+			return errors.New("contract is already closed")
+		}
+		contract.Status = models.ContractTerminated
+		return nil
+	})
+}
+
+func (s *ContractService) GetContracts(ctx context.Context, user *models.User) ([]models.Contract, error) {
+	switch user.UserType {
+	case "customer":
+		return s.contractRepository.GetContractsCustomers(ctx, user.UserID)
+	case "loader":
+		return s.contractRepository.GetContractsLoaders(ctx, user.UserID)
+	default:
+		return nil, errors.New("err")
+	}
+}
+
+func (s *ContractService) GetRoster(ctx context.Context, user *models.User) ([]models.RosterEntry, error) {
+	if user.UserType != "customer" {
+		return nil, errors.New("not customer")
+	}
+	return s.contractRepository.GetRoster(ctx, user.UserID)
+}
+
+func (s *ContractService) changeStatus(ctx context.Context, contractID uuid.UUID, change func(contract *models.Contract) error) error {
+	var status string
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		contract, err := s.contractRepository.GetContractByIDForUpdate(ctx, contractID)
+		if err != nil {
+			return err
+		}
+		err = change(contract)
+		if err != nil {
+			return err
+		}
+		status = contract.Status
+		return s.contractRepository.UpdateContract(ctx, contract)
+	})
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).With("contract_id", contractID, "status", status).Info("contract status changed")
+	return nil
+}
@@ -3,22 +3,25 @@ package jwtService
 import (
 	"errors"
 	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/clock"
 	"github.com/dgrijalva/jwt-go"
 	"time"
 )
 
 type JWTService struct {
 	secret string
+	clock  clock.Clock
 }
 
-func NewJWTService(secret string) *JWTService {
+func NewJWTService(secret string, clock clock.Clock) *JWTService {
 	return &JWTService{
 		secret: secret,
+		clock:  clock,
 	}
 }
 
 func (s *JWTService) GenerateToken(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(1 * time.Hour)
+	expirationTime := s.clock.Now().Add(1 * time.Hour)
 	claims := &models.Claims{
 		User: user,
 		StandardClaims: jwt.StandardClaims{
@@ -35,7 +38,9 @@ func (s *JWTService) GenerateToken(user *models.User) (string, error) {
 func (s *JWTService) ValidateToken(tokenString string) (*jwt.Token, error) {
 	claims := &models.Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+	// Срок действия проверяется по часам сервиса, а не по системному времени
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
@@ -49,6 +54,9 @@ func (s *JWTService) ValidateToken(tokenString string) (*jwt.Token, error) {
 	if !token.Valid {
 		return nil, errors.New("invalid token")
 	}
+	if !claims.VerifyExpiresAt(s.clock.Now().Unix(), false) {
+		return nil, errors.New("token is expired")
+	}
 
 	return token, nil
 }
@@ -0,0 +1,40 @@
+package jwtService_test
+
+import (
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/internal/service/jwtService"
+	"github.com/AhegaoHD/WBT/pkg/clock"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestTokenExpiresByServiceClock(t *testing.T) {
+	now := clock.NewFake(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC))
+	service := jwtService.NewJWTService("secret", now)
+
+	token, err := service.GenerateToken(&models.User{UserID: uuid.New(), UserType: "customer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		valid   bool
+	}{
+		{"fresh", 0, true},
+		{"before expiry", 59 * time.Minute, true},
+		{"after expiry", 2 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		now.Advance(tt.advance)
+		_, err := service.ValidateToken(token)
+		if tt.valid && err != nil {
+			t.Errorf("%s: err = %v, want valid token", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: token accepted, want expired", tt.name)
+		}
+	}
+}
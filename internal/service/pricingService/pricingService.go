@@ -0,0 +1,77 @@
+package pricingService
+
+import (
+	"fmt"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"math"
+	"time"
+)
+
+const (
+	ModelDefault = "default"
+	ModelFlat    = "flat"
+)
+
+// Input - все, от чего может зависеть цена грузчика за рейс.
+type Input struct {
+	Loader *models.Loader
+	// Demand - доля грузчиков, занятых по действующим договорам, от 0 до 1.
+	Demand float64
+	At     time.Time
+}
+
+// Pricer считает цену грузчика за один рейс.
+type Pricer interface {
+	Quote(in *Input) int
+}
+
+// NewPricer возвращает реализацию, выбранную в конфигурации.
+func NewPricer(cfg config.Pricing) (Pricer, error) {
+	switch cfg.Model {
+	case "", ModelDefault:
+		return &DefaultPricer{cfg: cfg}, nil
+	case ModelFlat:
+		return FlatPricer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown pricing model %q", cfg.Model)
+	}
+}
+
+// DefaultPricer берет за основу Salary грузчика и корректирует ее на спрос,
+// усталость, уровень грузчика и ночное время.
+type DefaultPricer struct {
+	cfg config.Pricing
+}
+
+func (p *DefaultPricer) Quote(in *Input) int {
+	price := float64(in.Loader.Salary)
+	price *= 1 + p.cfg.DemandFactor*in.Demand
+	price *= 1 - p.cfg.FatigueDiscount*float64(in.Loader.Fatigue)/100
+	price *= 1 + p.cfg.LevelPremium*float64(in.Loader.Level)
+	if p.isNight(in.At) {
+		price *= p.cfg.NightMultiplier
+	}
+	if price < 0 {
+		return 0
+	}
+	return int(math.Round(price))
+}
+
+func (p *DefaultPricer) isNight(at time.Time) bool {
+	if p.cfg.NightMultiplier == 0 || p.cfg.NightStartHour == p.cfg.NightEndHour {
+		return false
+	}
+	hour := at.Hour()
+	if p.cfg.NightStartHour < p.cfg.NightEndHour {
+		return hour >= p.cfg.NightStartHour && hour < p.cfg.NightEndHour
+	}
+	return hour >= p.cfg.NightStartHour || hour < p.cfg.NightEndHour
+}
+
+// FlatPricer всегда возвращает Salary грузчика, как было до появления динамических цен.
+type FlatPricer struct{}
+
+func (FlatPricer) Quote(in *Input) int {
+	return in.Loader.Salary
+}
@@ -0,0 +1,91 @@
+package reviewService
+
+import (
+	"context"
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/google/uuid"
+)
+
+type ReviewService struct {
+	tx               txManager
+	reviewRepository reviewRepository
+}
+
+type txManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type reviewRepository interface {
+	CreateReview(ctx context.Context, review *models.Review) (*models.Review, error)
+	GetTaskParticipants(ctx context.Context, taskID uuid.UUID) (*models.TaskParticipants, error)
+	GetReviewsByTarget(ctx context.Context, targetID uuid.UUID) ([]models.Review, error)
+}
+
+func NewReviewService(tx txManager, reviewRepository reviewRepository) *ReviewService {
+	return &ReviewService{tx: tx, reviewRepository: reviewRepository}
+}
+
+// CreateReview сохраняет оценку по выполненной задаче. Заказчик может оценить грузчиков,
+// работавших над задачей, грузчик - заказчика задачи. Повторная оценка того же участника
+// по той же задаче отклоняется ограничением в БД.
+func (s *ReviewService) CreateReview(ctx context.Context, req *models.CreateReviewRequest) (*models.Review, error) {
+	var review *models.Review
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		participants, err := s.reviewRepository.GetTaskParticipants(ctx, req.TaskID)
+		if err != nil {
+			return err
+		}
+		if !participants.Completed {
+			return errors.New("task is not completed")
+		}
+
+		switch req.User.UserType {
+		case "customer":
+			if participants.CustomerID != req.User.UserID {
+				return errors.New("task.CustomerID != req.User.UserID")
+			}
+			if !contains(participants.LoaderIDs, req.TargetID) {
+				return errors.New("target did not work on the task")
+			}
+		case "loader":
+			if !contains(participants.LoaderIDs, req.User.UserID) {
+				return errors.New("you did not work on the task")
+			}
+			if participants.CustomerID != req.TargetID {
+				return errors.New("target is not the task customer")
+			}
+		default:
+			return errors.New("err")
+		}
+
+		review, err = s.reviewRepository.CreateReview(ctx, &models.Review{
+			TaskID:   req.TaskID,
+			AuthorID: req.User.UserID,
+			TargetID: req.TargetID,
+			Rating:   req.Rating,
+			Comment:  req.Comment,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).With("task_id", req.TaskID, "target_id", req.TargetID).Info("review created")
+	return review, nil
+}
+
+// GetReviews возвращает отзывы о пользователе.
+func (s *ReviewService) GetReviews(ctx context.Context, user *models.User) ([]models.Review, error) {
+	return s.reviewRepository.GetReviewsByTarget(ctx, user.UserID)
+}
+
+func contains(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package rulesService
+
+import (
+	"fmt"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"sort"
+)
+
+const (
+	RulesetDefault   = "default"
+	RulesetRelaxed   = "relaxed"
+	RulesetPiecework = "piecework"
+)
+
+// Rules - правила игры, по которым выполняется рейс.
+type Rules interface {
+	// Capacity - сколько грузчик может перенести за рейс.
+	Capacity(loader *models.Loader) int
+	// ApplyFatigue начисляет грузчику усталость после рейса.
+	ApplyFatigue(loader *models.Loader)
+	// Cost - сколько заказчик платит грузчику за рейс при согласованной цене price.
+	// Вызывается до начисления усталости.
+	Cost(loader *models.Loader, price int) int
+	// Eligible возвращает ошибку, если грузчик не допущен к задаче.
+	Eligible(task *models.Task, loader *models.Loader) error
+}
+
+// Registry хранит все наборы правил; набор выбирается заказчиком на всю игру.
+type Registry struct {
+	rulesets    map[string]Rules
+	defaultName string
+}
+
+// NewRegistry собирает наборы правил по конфигурации. Набор по умолчанию берется из Game.Ruleset.
+func NewRegistry(game config.Game, cargoTypes []config.CargoType) (*Registry, error) {
+	cargoTypesByName := make(map[string]config.CargoType, len(cargoTypes))
+	for _, cargo := range cargoTypes {
+		cargoTypesByName[cargo.Name] = cargo
+	}
+
+	base := &DefaultRules{game: game, cargoTypes: cargoTypesByName}
+	r := &Registry{
+		rulesets: map[string]Rules{
+			RulesetDefault:   base,
+			RulesetRelaxed:   &RelaxedRules{DefaultRules: base},
+			RulesetPiecework: &PieceworkRules{DefaultRules: base},
+		},
+		defaultName: RulesetDefault,
+	}
+	if game.Ruleset != "" {
+		name, err := r.Name(game.Ruleset)
+		if err != nil {
+			return nil, err
+		}
+		r.defaultName = name
+	}
+	return r, nil
+}
+
+// Name проверяет имя набора правил; пустое имя означает набор по умолчанию.
+func (r *Registry) Name(name string) (string, error) {
+	if name == "" {
+		return r.defaultName, nil
+	}
+	if _, ok := r.rulesets[name]; !ok {
+		return "", fmt.Errorf("unknown ruleset %q", name)
+	}
+	return name, nil
+}
+
+func (r *Registry) Get(name string) (Rules, error) {
+	name, err := r.Name(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.rulesets[name], nil
+}
+
+// Names возвращает имена всех наборов правил по алфавиту.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.rulesets))
+	for name := range r.rulesets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultRules - исходные правила: усталость пропорционально снижает грузоподъемность,
+// грузчику платится согласованная цена, допуск определяется типом груза.
+type DefaultRules struct {
+	game       config.Game
+	cargoTypes map[string]config.CargoType
+}
+
+func (r *DefaultRules) Capacity(loader *models.Loader) int {
+	if loader.Fatigue >= 100 {
+		return 0
+	}
+	return loader.MaxWeight * (100 - loader.Fatigue) / 100
+}
+
+func (r *DefaultRules) ApplyFatigue(loader *models.Loader) {
+	if loader.Drunk {
+		loader.Fatigue += r.game.FatigueDrunk
+	} else {
+		loader.Fatigue += r.game.FatigueSober
+	}
+	if loader.Fatigue > 100 {
+		loader.Fatigue = 100
+	}
+}
+
+func (r *DefaultRules) Cost(loader *models.Loader, price int) int {
+	return price
+}
+
+// Eligible проверяет ограничения типа груза задачи. Общий груз ограничений не имеет.
+func (r *DefaultRules) Eligible(task *models.Task, loader *models.Loader) error {
+	if task.CargoType == "" {
+		return nil
+	}
+	cargo, ok := r.cargoTypes[task.CargoType]
+	if !ok {
+		return fmt.Errorf("unknown cargo type %q", task.CargoType)
+	}
+	if cargo.ForbidDrunk && loader.Drunk {
+		return fmt.Errorf("drunk loaders are not allowed for %s cargo", cargo.Name)
+	}
+	if loader.MaxWeight < cargo.MinLoaderMaxWeight {
+		return fmt.Errorf("%s cargo needs MaxWeight >= %d", cargo.Name, cargo.MinLoaderMaxWeight)
+	}
+	if cargo.MaxLoaderFatigue != nil && loader.Fatigue > *cargo.MaxLoaderFatigue {
+		return fmt.Errorf("%s cargo needs Fatigue <= %d", cargo.Name, *cargo.MaxLoaderFatigue)
+	}
+	return nil
+}
+
+// RelaxedRules - усталость не снижает грузоподъемность, пока грузчик не вымотан полностью.
+type RelaxedRules struct {
+	*DefaultRules
+}
+
+func (r *RelaxedRules) Capacity(loader *models.Loader) int {
+	if loader.Fatigue >= 100 {
+		return 0
+	}
+	return loader.MaxWeight
+}
+
+// PieceworkRules - сдельная оплата: грузчик получает долю цены, равную доле
+// своей полной грузоподъемности, которую он может перенести с учетом усталости.
+type PieceworkRules struct {
+	*DefaultRules
+}
+
+func (r *PieceworkRules) Cost(loader *models.Loader, price int) int {
+	if loader.MaxWeight == 0 {
+		return 0
+	}
+	return price * r.Capacity(loader) / loader.MaxWeight
+}
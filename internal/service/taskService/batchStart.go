@@ -0,0 +1,115 @@
+package taskService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AhegaoHD/WBT/internal/lockorder"
+	"github.com/AhegaoHD/WBT/internal/metrics"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// StartTasks запускает несколько задач в одной транзакции.
+//
+// Перед обработкой все задачи и грузчики пакета блокируются в порядке возрастания
+// идентификаторов (задачи, затем заказчик, затем грузчики - тот же порядок, что и в StartTask),
+// поэтому параллельные пакеты с пересекающимися грузчиками не блокируют друг друга навечно.
+//
+// В режиме BatchModeAllOrNothing первая ошибка откатывает весь пакет, в режиме
+// BatchModeBestEffort каждая задача выполняется в своей точке сохранения и
+// ошибка одной задачи не влияет на остальные.
+func (s *TaskService) StartTasks(ctx context.Context, req *models.BatchStartTaskRequest) (*models.BatchStartTaskResult, error) {
+	if req.User.UserType != "customer" {
+		return nil, errors.New("not customer")
+	}
+
+	taskIDs := make([]uuid.UUID, 0, len(req.Tasks))
+	var loaderIDs []uuid.UUID
+	for _, item := range req.Tasks {
+		taskIDs = append(taskIDs, item.TaskID)
+		loaderIDs = append(loaderIDs, item.LoaderIDs...)
+	}
+
+	result := &models.BatchStartTaskResult{Results: make([]models.BatchStartTaskItemResult, 0, len(req.Tasks))}
+	// itemErr - ошибка задачи, откатившая пакет BatchModeAllOrNothing; тогда вызывающий получает и результаты
+	var itemErr error
+	// spent - капитал, списанный за рейсы пакета, и failed - причины отказов по задачам
+	var spent int
+	var failed []string
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// Транзакция может выполниться повторно после deadlock - результаты прошлой попытки отброшены
+		result.Results, itemErr = result.Results[:0], nil
+		spent, failed = 0, failed[:0]
+
+		err := s.taskRepository.LockTasks(ctx, lockorder.Sorted(taskIDs))
+		if err != nil {
+			return err
+		}
+		_, err = s.customerRepository.GetCustomerByIDForUpdate(ctx, req.User.UserID)
+		if err != nil {
+			return err
+		}
+		err = s.loaderRepository.LockLoaders(ctx, lockorder.Sorted(loaderIDs))
+		if err != nil {
+			return err
+		}
+
+		for i := range req.Tasks {
+			item := req.Tasks[i]
+			item.User = req.User
+
+			var itemSpent int
+			switch req.Mode {
+			case models.BatchModeAllOrNothing:
+				itemSpent, err = s.startTask(ctx, &item)
+			case models.BatchModeBestEffort:
+				err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+					var err error
+					itemSpent, err = s.startTask(ctx, &item)
+					return err
+				})
+			default:
+				return errors.New("unknown batch mode")
+			}
+
+			itemResult := models.BatchStartTaskItemResult{TaskID: item.TaskID, Started: err == nil}
+			if err != nil {
+				itemResult.Error = err.Error()
+				failed = append(failed, failureReason(err))
+			} else {
+				spent += itemSpent
+			}
+			result.Results = append(result.Results, itemResult)
+
+			if err != nil && req.Mode == models.BatchModeAllOrNothing {
+				itemErr = fmt.Errorf("task %s: %w", item.TaskID, err)
+				return itemErr
+			}
+		}
+		return nil
+	})
+	if itemErr != nil {
+		metrics.TasksFailed.WithLabelValues(failureReason(itemErr)).Inc()
+		logger.FromContext(ctx).With("mode", req.Mode, "tasks", len(req.Tasks)).Debug("batch rolled back: %v", itemErr)
+		return result, itemErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Committed = true
+
+	started := 0
+	for _, item := range result.Results {
+		if item.Started {
+			started++
+		}
+	}
+	for _, reason := range failed {
+		metrics.TasksFailed.WithLabelValues(reason).Inc()
+	}
+	recordTrips(started, spent)
+	logger.FromContext(ctx).With("mode", req.Mode, "tasks", len(req.Tasks), "started", started).Info("batch started")
+	return result, nil
+}
@@ -0,0 +1,189 @@
+package taskService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AhegaoHD/WBT/internal/metrics"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// PublishTask открывает задачу для откликов грузчиков.
+func (s *TaskService) PublishTask(ctx context.Context, req *models.PublishTaskRequest) error {
+	if req.User.UserType != "customer" {
+		return errors.New("not customer")
+	}
+
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		task, err := s.taskRepository.GetTaskByIDForUpdate(ctx, req.TaskID)
+		if err != nil {
+			return err
+		}
+		if task.CustomerID != req.User.UserID {
+			return errors.New("task.CustomerID != req.User.UserID")
+		}
+		if task.Status {
+			return errors.New("уже выполнена")
+		}
+		if req.Version != nil && *req.Version != task.Version {
+			return models.ErrVersionMismatch
+		}
+
+		task.Published = true
+		return s.taskRepository.UpdateTask(ctx, task)
+	})
+}
+
+func (s *TaskService) GetBoard(ctx context.Context) ([]models.Task, error) {
+	return s.taskRepository.GetPublishedTasks(ctx)
+}
+
+// Apply регистрирует отклик грузчика на опубликованную задачу.
+func (s *TaskService) Apply(ctx context.Context, req *models.ApplyRequest) (*models.Application, error) {
+	if req.User.UserType != "loader" {
+		return nil, errors.New("not loader")
+	}
+
+	var application *models.Application
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		task, err := s.taskRepository.GetTaskByIDForUpdate(ctx, req.TaskID)
+		if err != nil {
+			return err
+		}
+		if !task.Published || task.Status {
+			return errors.New("task is not open for applications")
+		}
+
+		application, err = s.applicationRepository.CreateApplication(ctx, &models.Application{
+			TaskID:      req.TaskID,
+			LoaderID:    req.User.UserID,
+			AskingPrice: req.AskingPrice,
+			Status:      models.ApplicationPending,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return application, nil
+}
+
+// GetApplications возвращает заказчику отклики на его задачу, а грузчику - его собственные отклики.
+func (s *TaskService) GetApplications(ctx context.Context, user *models.User, taskID uuid.UUID) ([]models.Application, error) {
+	switch user.UserType {
+	case "customer":
+		return s.applicationRepository.GetApplicationsCustomers(ctx, user.UserID, taskID)
+	case "loader":
+		return s.applicationRepository.GetApplicationsLoaders(ctx, user.UserID)
+	default:
+		return nil, errors.New("err")
+	}
+}
+
+// ConfirmCrew собирает бригаду из выбранных откликов и выполняет рейс по правилам StartTask.
+// Каждому грузчику платится указанная в отклике цена, а грузчику с действующим договором
+// с заказчиком - цена по договору, как в StartTask. Когда задача выполнена, она снимается
+// с доски, а остальные ожидающие отклики отклоняются.
+func (s *TaskService) ConfirmCrew(ctx context.Context, req *models.ConfirmCrewRequest) error {
+	var spent int
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		spent, err = s.confirmCrew(ctx, req)
+		return err
+	})
+	l := logger.FromContext(ctx).With("task_id", req.TaskID, "applications", len(req.ApplicationIDs))
+	if err != nil {
+		metrics.TasksFailed.WithLabelValues(failureReason(err)).Inc()
+		l.Debug("crew not confirmed: %v", err)
+		return err
+	}
+	recordTrips(1, spent)
+	l.Info("crew confirmed")
+	return nil
+}
+
+// confirmCrew выполняет все проверки и изменения ConfirmCrew внутри транзакции из ctx
+// и возвращает капитал, выплаченный бригаде за рейс.
+func (s *TaskService) confirmCrew(ctx context.Context, req *models.ConfirmCrewRequest) (int, error) {
+	if req.User.UserType != "customer" {
+		return 0, reject(reasonNotCustomer, errors.New("not customer"))
+	}
+
+	task, err := s.taskRepository.GetTaskByIDForUpdate(ctx, req.TaskID)
+	if err != nil {
+		return 0, err
+	}
+	if task.CustomerID != req.User.UserID {
+		return 0, reject(reasonNotOwner, errors.New("task.CustomerID != req.User.UserID"))
+	}
+	if task.Status {
+		return 0, reject(reasonCompleted, errors.New("task is not open for applications"))
+	}
+	if !task.Published {
+		return 0, reject(reasonUnpublished, errors.New("task is not open for applications"))
+	}
+	if req.Version != nil && *req.Version != task.Version {
+		return 0, models.ErrVersionMismatch
+	}
+	customer, err := s.customerRepository.GetCustomerByIDForUpdate(ctx, req.User.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	applications, err := s.applicationRepository.GetApplicationsByIDsForUpdate(ctx, req.ApplicationIDs)
+	if err != nil {
+		return 0, err
+	}
+	pay := make(map[uuid.UUID]int, len(applications))
+	loaderIDs := make([]uuid.UUID, 0, len(applications))
+	for _, application := range applications {
+		if application.TaskID != task.TaskID {
+			return 0, fmt.Errorf("application %s is for another task", application.ApplicationID)
+		}
+		if application.Status != models.ApplicationPending {
+			return 0, fmt.Errorf("application %s is not pending", application.ApplicationID)
+		}
+		pay[application.LoaderID] = application.AskingPrice
+		loaderIDs = append(loaderIDs, application.LoaderID)
+	}
+
+	loaders, err := s.loaderRepository.GetLoadersByIDsForUpdate(ctx, loaderIDs)
+	if err != nil {
+		return 0, err
+	}
+	contracted, err := s.contractRates(ctx, task, loaders)
+	if err != nil {
+		return 0, err
+	}
+	for loaderID, price := range contracted {
+		pay[loaderID] = price
+	}
+	rules, err := s.rulesFor(customer)
+	if err != nil {
+		return 0, err
+	}
+	err = checkEligible(rules, task, loaders)
+	if err != nil {
+		return 0, err
+	}
+
+	spent, err := s.runTrip(ctx, rules, task, customer, loaders, pay)
+	if err != nil {
+		return 0, err
+	}
+
+	if task.Status {
+		task.Published = false
+		err = s.taskRepository.UpdateTask(ctx, task)
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = s.applicationRepository.CloseApplications(ctx, task.TaskID, req.ApplicationIDs, task.Status)
+	if err != nil {
+		return 0, err
+	}
+	return spent, nil
+}
@@ -0,0 +1,95 @@
+package taskService
+
+import (
+	"context"
+	"fmt"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/google/uuid"
+)
+
+// contractPay проверяет, что все грузчики работают у заказчика по действующему договору,
+// и возвращает оплату каждого из них за рейс.
+func (s *TaskService) contractPay(ctx context.Context, task *models.Task, loaders []models.Loader) (map[uuid.UUID]int, error) {
+	pay, err := s.contractRates(ctx, task, loaders)
+	if err != nil {
+		return nil, err
+	}
+	for i := range loaders {
+		if _, ok := pay[loaders[i].LoaderID]; !ok {
+			return nil, reject(reasonNoContract, fmt.Errorf("loader %s is not under contract", loaders[i].LoaderID))
+		}
+	}
+	return pay, nil
+}
+
+// contractRates возвращает оплату за рейс тех грузчиков, у которых с заказчиком задачи есть
+// действующий договор. Для договоров по рыночной цене берется цена, зафиксированная за
+// грузчиком в задаче, а если он назначается впервые - текущая котировка.
+func (s *TaskService) contractRates(ctx context.Context, task *models.Task, loaders []models.Loader) (map[uuid.UUID]int, error) {
+	loaderIDs := make([]uuid.UUID, 0, len(loaders))
+	for i := range loaders {
+		loaderIDs = append(loaderIDs, loaders[i].LoaderID)
+	}
+	contracts, err := s.contractRepository.GetActiveContracts(ctx, task.CustomerID, loaderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	market := make(map[uuid.UUID]bool)
+	pay := make(map[uuid.UUID]int, len(contracts))
+	for i := range contracts {
+		if contracts[i].MarketRate() {
+			market[contracts[i].LoaderID] = true
+		}
+		pay[contracts[i].LoaderID] = contracts[i].TaskCost()
+	}
+	if len(market) == 0 {
+		return pay, nil
+	}
+
+	locked, err := s.taskRepository.GetTaskLoaderPrices(ctx, task.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	q, err := s.newQuoter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range loaders {
+		loaderID := loaders[i].LoaderID
+		if !market[loaderID] {
+			continue
+		}
+		if price, ok := locked[loaderID]; ok {
+			pay[loaderID] = price
+		} else {
+			pay[loaderID] = q.quote(&loaders[i])
+		}
+	}
+	return pay, nil
+}
+
+// rosterLoaders возвращает штат заказчика для подбора бригад. Salary каждого
+// грузчика заменена оплатой за рейс по договору; эти данные не сохраняются.
+func (s *TaskService) rosterLoaders(ctx context.Context, customerID uuid.UUID) ([]models.Loader, error) {
+	roster, err := s.contractRepository.GetRoster(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	q, err := s.newQuoter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loaders := make([]models.Loader, 0, len(roster))
+	for i := range roster {
+		loader := roster[i].Loader
+		if roster[i].Contract.MarketRate() {
+			loader.Salary = q.quote(&roster[i].Loader)
+		} else {
+			loader.Salary = roster[i].Contract.TaskCost()
+		}
+		loaders = append(loaders, loader)
+	}
+	return loaders, nil
+}
@@ -0,0 +1,71 @@
+package taskService
+
+import (
+	"encoding/binary"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/random"
+	"github.com/google/uuid"
+)
+
+type randomSource interface {
+	Float64() float64
+}
+
+// tripRandom возвращает генератор порчи груза для рейса по задаче в ее текущей версии.
+// Генератор зависит только от seed игры, задачи и версии, поэтому повтор транзакции
+// и перезапуск приложения бросают те же кости, а рейсы разных задач не влияют друг на друга.
+func (s *TaskService) tripRandom(task *models.Task) randomSource {
+	id := task.TaskID
+	taskSeed := s.damageSeed ^ int64(binary.BigEndian.Uint64(id[:8])^binary.BigEndian.Uint64(id[8:]))
+	return random.New(random.Derive(taskSeed, int64(task.Version)))
+}
+
+// rollDamage решает, испортил ли грузчик груз в этом рейсе.
+// Вероятность считается по состоянию грузчика до рейса.
+func (s *TaskService) rollDamage(rnd randomSource, task *models.Task, loader *models.Loader) *models.DamageEvent {
+	p := damageProbability(&s.damage, loader)
+	if p == 0 || rnd.Float64() >= p {
+		return nil
+	}
+	return &models.DamageEvent{
+		TaskID:    task.TaskID,
+		LoaderID:  loader.LoaderID,
+		Penalty:   s.damage.Penalty,
+		ChargedTo: s.damage.ChargeTo,
+	}
+}
+
+// settleDamages списывает штрафы за порчу груза с заказчика или из оплаты грузчика
+// и записывает итог на задачу. Штраф не может превышать остаток капитала заказчика
+// или оплату грузчика за рейс, в событии сохраняется фактически списанная сумма.
+func settleDamages(task *models.Task, customer *models.Customer, pay map[uuid.UUID]int, damages []models.DamageEvent) {
+	for i := range damages {
+		damage := &damages[i]
+		switch damage.ChargedTo {
+		case config.ChargeToCustomer:
+			if damage.Penalty > customer.Capital {
+				damage.Penalty = customer.Capital
+			}
+			customer.Capital -= damage.Penalty
+		case config.ChargeToLoader:
+			// Заказчик не платит грузчику удержанную часть зарплаты
+			if damage.Penalty > pay[damage.LoaderID] {
+				damage.Penalty = pay[damage.LoaderID]
+			}
+			customer.Capital += damage.Penalty
+		}
+		task.DamagePenalty += damage.Penalty
+	}
+}
+
+func damageProbability(damage *config.Damage, loader *models.Loader) float64 {
+	safe := 1.0
+	if loader.Drunk {
+		safe *= 1 - damage.DrunkProbability
+	}
+	if loader.Fatigue >= damage.FatigueThreshold {
+		safe *= 1 - damage.FatigueProbability
+	}
+	return 1 - safe
+}
@@ -0,0 +1,147 @@
+package taskService
+
+import (
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/random"
+	"github.com/google/uuid"
+	"testing"
+)
+
+// rolls - число рейсов в табличных тестах rollDamage.
+const rolls = 200
+
+func TestTripRandomRepeatsForSameTaskVersion(t *testing.T) {
+	s := &TaskService{damageSeed: 42}
+	task := &models.Task{TaskID: uuid.New(), Version: 3}
+	draws := func(task *models.Task) [4]float64 {
+		rnd := s.tripRandom(task)
+		var d [4]float64
+		for i := range d {
+			d[i] = rnd.Float64()
+		}
+		return d
+	}
+
+	// Повтор транзакции видит ту же версию задачи и бросает те же кости
+	first := draws(task)
+	if again := draws(task); again != first {
+		t.Errorf("retry draws %v, first attempt %v", again, first)
+	}
+
+	next := *task
+	next.Version++
+	if draws(&next) == first {
+		t.Error("next trip repeats the draws of the previous one")
+	}
+	other := *task
+	other.TaskID = uuid.New()
+	if draws(&other) == first {
+		t.Error("another task repeats the draws")
+	}
+}
+
+func TestRollDamage(t *testing.T) {
+	risky := config.Damage{DrunkProbability: 0.5, FatigueThreshold: 70, FatigueProbability: 0.5, Penalty: 300, ChargeTo: config.ChargeToLoader}
+	tests := []struct {
+		name   string
+		damage config.Damage
+		loader models.Loader
+		want   int // сколько из rolls рейсов с порчей
+	}{
+		{"sober and rested", risky, models.Loader{Fatigue: 69}, 0},
+		{"fatigue threshold is inclusive", config.Damage{FatigueThreshold: 70, FatigueProbability: 1, Penalty: 300}, models.Loader{Fatigue: 70}, rolls},
+		{"certain for drunk", config.Damage{DrunkProbability: 1, FatigueThreshold: 70, Penalty: 300}, models.Loader{Drunk: true}, rolls},
+		{"drunk", risky, models.Loader{Drunk: true}, 95},
+		{"drunk and tired", risky, models.Loader{Drunk: true, Fatigue: 100}, 137},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &TaskService{damage: tt.damage}
+			task := &models.Task{TaskID: uuid.New()}
+			loader := tt.loader
+			loader.LoaderID = uuid.New()
+			rnd := random.New(7)
+
+			got := 0
+			for i := 0; i < rolls; i++ {
+				damage := s.rollDamage(rnd, task, &loader)
+				if damage == nil {
+					continue
+				}
+				got++
+				if damage.TaskID != task.TaskID || damage.LoaderID != loader.LoaderID || damage.Penalty != tt.damage.Penalty || damage.ChargedTo != tt.damage.ChargeTo {
+					t.Fatalf("damage = %+v", damage)
+				}
+			}
+			if got != tt.want {
+				t.Errorf("%d of %d trips damaged, want %d", got, rolls, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettleDamages(t *testing.T) {
+	loaderA, loaderB := uuid.New(), uuid.New()
+	pay := map[uuid.UUID]int{loaderA: 500, loaderB: 200}
+	tests := []struct {
+		name        string
+		capital     int
+		damages     []models.DamageEvent
+		wantCapital int
+		wantCharged []int
+	}{
+		{
+			name:        "customer pays",
+			capital:     1000,
+			damages:     []models.DamageEvent{{LoaderID: loaderA, Penalty: 300, ChargedTo: config.ChargeToCustomer}},
+			wantCapital: 700,
+			wantCharged: []int{300},
+		},
+		{
+			name:    "customer penalty capped by capital",
+			capital: 400,
+			damages: []models.DamageEvent{
+				{LoaderID: loaderA, Penalty: 300, ChargedTo: config.ChargeToCustomer},
+				{LoaderID: loaderB, Penalty: 300, ChargedTo: config.ChargeToCustomer},
+			},
+			wantCapital: 0,
+			wantCharged: []int{300, 100},
+		},
+		{
+			name:        "loader pays from trip pay",
+			capital:     1000,
+			damages:     []models.DamageEvent{{LoaderID: loaderA, Penalty: 300, ChargedTo: config.ChargeToLoader}},
+			wantCapital: 1300,
+			wantCharged: []int{300},
+		},
+		{
+			name:        "loader penalty capped by trip pay",
+			capital:     1000,
+			damages:     []models.DamageEvent{{LoaderID: loaderB, Penalty: 300, ChargedTo: config.ChargeToLoader}},
+			wantCapital: 1200,
+			wantCharged: []int{200},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.Task{}
+			customer := &models.Customer{Capital: tt.capital}
+			settleDamages(task, customer, pay, tt.damages)
+
+			if customer.Capital != tt.wantCapital {
+				t.Errorf("capital = %d, want %d", customer.Capital, tt.wantCapital)
+			}
+			total := 0
+			for i, want := range tt.wantCharged {
+				if tt.damages[i].Penalty != want {
+					t.Errorf("damage %d: penalty = %d, want %d", i, tt.damages[i].Penalty, want)
+				}
+				total += want
+			}
+			if task.DamagePenalty != total {
+				t.Errorf("task penalty = %d, want %d", task.DamagePenalty, total)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package taskService
+
+import (
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/metrics"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/pkg/retry"
+)
+
+// Причины отказа StartTask и ConfirmCrew - метка reason метрики wbt_tasks_failed_total.
+const (
+	reasonNotCustomer = "not_customer"
+	reasonNotOwner    = "not_owner"
+	reasonCompleted   = "completed"
+	reasonUnpublished = "unpublished"
+	reasonNotEligible = "not_eligible"
+	reasonNoContract  = "no_contract"
+	reasonNoCapacity  = "no_capacity"
+	reasonCapital     = "capital"
+	reasonConflict    = "conflict"
+	reasonOther       = "other"
+)
+
+// rejectedError - отказ с известной причиной. Текст ошибки для клиента не меняется.
+type rejectedError struct {
+	reason string
+	err    error
+}
+
+func (e *rejectedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rejectedError) Unwrap() error {
+	return e.err
+}
+
+func reject(reason string, err error) error {
+	return &rejectedError{reason: reason, err: err}
+}
+
+// Rejected сообщает, что StartTask или ConfirmCrew отказали по правилам игры (не хватает
+// капитала, грузчик не подходит и т.п.), а не из-за ошибки хранилища или конфликта.
+func Rejected(err error) bool {
+	var rejected *rejectedError
+	return errors.As(err, &rejected)
+}
+
+// failureReason возвращает причину отказа StartTask или ConfirmCrew для метрик.
+func failureReason(err error) string {
+	var rejected *rejectedError
+	switch {
+	case errors.As(err, &rejected):
+		return rejected.reason
+	case errors.Is(err, models.ErrVersionMismatch) || retry.Retryable(err):
+		return reasonConflict
+	default:
+		return reasonOther
+	}
+}
+
+// recordTrips учитывает зафиксированные рейсы и выплаченный за них капитал.
+func recordTrips(trips, spent int) {
+	metrics.TasksStarted.Add(float64(trips))
+	metrics.CapitalSpent.Add(float64(spent))
+}
@@ -0,0 +1,307 @@
+package taskService
+
+import (
+	"context"
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/internal/service/rulesService"
+	"github.com/google/uuid"
+	"math"
+	"sort"
+)
+
+// maxExhaustiveTasks - до какого количества задач планировщик перебирает все порядки
+// выполнения. Для большего количества используется жадный порядок по возрастанию веса.
+const maxExhaustiveTasks = 7
+
+// maxSearchCells - предел работы полного перебора: суммарное число клеток таблиц cheapestCrew
+// во всех узлах перебора. Если оценка больше (большой штат или тяжелые задачи), план
+// строится жадно, чтобы один GET /plan не выделял сотни мегабайт.
+const maxSearchCells = 1 << 21
+
+// PlanTasks строит план распределения грузчиков из штата заказчика по всем невыполненным задачам заказчика.
+// План максимизирует число задач, выполненных за один рейс в пределах капитала, а при равном
+// числе задач - минимизирует суммарные расходы. Задачам, которые штат не может закончить за
+// один рейс, на оставшийся капитал назначается частичный рейс самой сильной бригадой.
+// Рост усталости после каждой задачи учитывается так же, как в StartTask. План ничего не меняет в базе: его можно просмотреть и затем
+// выполнить через ExecutePlan.
+func (s *TaskService) PlanTasks(ctx context.Context, user *models.User) (*models.Plan, error) {
+	if user.UserType != "customer" {
+		return nil, errors.New("not customer")
+	}
+
+	customer, err := s.customerRepository.GetCustomerByID(ctx, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.rulesFor(customer)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.taskRepository.GetTaskQueue(ctx, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	loaders, err := s.rosterLoaders(ctx, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildPlan(tasks, loaders, customer.Capital, rules), nil
+}
+
+// ExecutePlan атомарно выполняет план: либо запускаются все задачи плана, либо ни одна.
+// Все правила StartTask проверяются заново под блокировками, поэтому устаревший план
+// (например, если грузчики успели устать) будет отклонен целиком.
+func (s *TaskService) ExecutePlan(ctx context.Context, user *models.User, plan *models.Plan) (*models.BatchStartTaskResult, error) {
+	req := &models.BatchStartTaskRequest{
+		User:  user,
+		Mode:  models.BatchModeAllOrNothing,
+		Tasks: make([]models.StartTaskRequest, 0, len(plan.Assignments)),
+	}
+	for _, assignment := range plan.Assignments {
+		req.Tasks = append(req.Tasks, models.StartTaskRequest{TaskID: assignment.TaskID, LoaderIDs: assignment.LoaderIDs})
+	}
+
+	return s.StartTasks(ctx, req)
+}
+
+func buildPlan(tasks []models.Task, loaders []models.Loader, capital int, rules rulesService.Rules) *models.Plan {
+	var assignments []models.PlanAssignment
+	if len(tasks) <= maxExhaustiveTasks && searchCells(tasks, loaders) <= maxSearchCells {
+		assignments, _ = searchPlan(tasks, loaders, capital, rules)
+	} else {
+		assignments = greedyPlan(tasks, loaders, capital, rules)
+	}
+
+	assignments = append(assignments, partialTrips(tasks, loaders, capital, rules, assignments)...)
+
+	plan := &models.Plan{Assignments: []models.PlanAssignment{}, CapitalLeft: capital}
+	for _, assignment := range assignments {
+		plan.Assignments = append(plan.Assignments, assignment)
+		plan.TotalCost += assignment.Cost
+	}
+	plan.CapitalLeft -= plan.TotalCost
+	return plan
+}
+
+// searchCells оценивает сверху работу searchPlan: число вызовов cheapestCrew, умноженное
+// на размер его таблиц для самой тяжелой задачи.
+func searchCells(tasks []models.Task, loaders []models.Loader) int {
+	nodes, orders := 0, 1
+	for i := len(tasks); i > 0; i-- {
+		orders *= i
+		nodes += orders
+	}
+	maxWeight := 1
+	for i := range tasks {
+		if tasks[i].RemainingWeight > maxWeight {
+			maxWeight = tasks[i].RemainingWeight
+		}
+	}
+	return nodes * (len(loaders) + 1) * (maxWeight + 1)
+}
+
+// searchPlan перебирает все порядки выполнения задач и возвращает лучший план и его стоимость.
+func searchPlan(tasks []models.Task, loaders []models.Loader, capital int, rules rulesService.Rules) ([]models.PlanAssignment, int) {
+	var best []models.PlanAssignment
+	var bestCost int
+
+	for i := range tasks {
+		assignment, next, ok := assignCrew(&tasks[i], loaders, capital, rules)
+		if !ok {
+			continue
+		}
+
+		rest := make([]models.Task, 0, len(tasks)-1)
+		rest = append(rest, tasks[:i]...)
+		rest = append(rest, tasks[i+1:]...)
+		sub, subCost := searchPlan(rest, next, capital-assignment.Cost, rules)
+
+		cost := assignment.Cost + subCost
+		if len(sub)+1 > len(best) || (len(sub)+1 == len(best) && cost < bestCost) {
+			best = append([]models.PlanAssignment{*assignment}, sub...)
+			bestCost = cost
+		}
+	}
+
+	return best, bestCost
+}
+
+// greedyPlan назначает бригады задачам по возрастанию веса.
+func greedyPlan(tasks []models.Task, loaders []models.Loader, capital int, rules rulesService.Rules) []models.PlanAssignment {
+	ordered := make([]models.Task, len(tasks))
+	copy(ordered, tasks)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].RemainingWeight < ordered[j].RemainingWeight
+	})
+
+	var assignments []models.PlanAssignment
+	for i := range ordered {
+		assignment, next, ok := assignCrew(&ordered[i], loaders, capital, rules)
+		if !ok {
+			continue
+		}
+		assignments = append(assignments, *assignment)
+		loaders = next
+		capital -= assignment.Cost
+	}
+	return assignments
+}
+
+// partialTrips назначает частичные рейсы задачам, которые штат не может закончить за один
+// рейс, после рейсов planned - с учетом усталости и капитала, которые останутся после них.
+func partialTrips(tasks []models.Task, loaders []models.Loader, capital int, rules rulesService.Rules, planned []models.PlanAssignment) []models.PlanAssignment {
+	state := make([]models.Loader, len(loaders))
+	copy(state, loaders)
+	index := make(map[uuid.UUID]int, len(state))
+	for i := range state {
+		index[state[i].LoaderID] = i
+	}
+	done := make(map[uuid.UUID]bool, len(planned))
+	for _, assignment := range planned {
+		done[assignment.TaskID] = true
+		capital -= assignment.Cost
+		for _, loaderID := range assignment.LoaderIDs {
+			rules.ApplyFatigue(&state[index[loaderID]])
+		}
+	}
+
+	var trips []models.PlanAssignment
+	for i := range tasks {
+		task := &tasks[i]
+		if done[task.TaskID] {
+			continue
+		}
+		eligible := func(loader *models.Loader) bool {
+			return rules.Eligible(task, loader) == nil
+		}
+		// Задача, которую можно закончить за рейс, осталась вне плана из-за капитала
+		if _, _, ok := cheapestCrew(state, task.RemainingWeight, rules, eligible); ok {
+			continue
+		}
+		crew, cost, ok := partialCrew(task, state, capital, rules)
+		if !ok {
+			continue
+		}
+		loaderIDs := make([]uuid.UUID, 0, len(crew))
+		for _, j := range crew {
+			rules.ApplyFatigue(&state[j])
+			loaderIDs = append(loaderIDs, state[j].LoaderID)
+		}
+		capital -= cost
+		trips = append(trips, models.PlanAssignment{TaskID: task.TaskID, LoaderIDs: loaderIDs, Cost: cost, Partial: true})
+	}
+	return trips
+}
+
+// partialCrew набирает бригаду для рейса по задаче, которую нельзя закончить за один рейс:
+// допущенных грузчиков по убыванию грузоподъемности, пока на их оплату хватает капитала.
+// Возвращает индексы выбранных грузчиков и стоимость рейса.
+func partialCrew(task *models.Task, loaders []models.Loader, capital int, rules rulesService.Rules) ([]int, int, bool) {
+	order := make([]int, 0, len(loaders))
+	for i := range loaders {
+		if rules.Capacity(&loaders[i]) > 0 && rules.Eligible(task, &loaders[i]) == nil {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rules.Capacity(&loaders[order[a]]) > rules.Capacity(&loaders[order[b]])
+	})
+
+	var crew []int
+	var cost int
+	for _, i := range order {
+		c := rules.Cost(&loaders[i], loaders[i].Salary)
+		if cost+c > capital {
+			continue
+		}
+		crew = append(crew, i)
+		cost += c
+	}
+	return crew, cost, len(crew) > 0
+}
+
+// assignCrew подбирает самую дешевую бригаду для задачи и возвращает состояние
+// грузчиков после ее выполнения.
+func assignCrew(task *models.Task, loaders []models.Loader, capital int, rules rulesService.Rules) (*models.PlanAssignment, []models.Loader, bool) {
+	crew, cost, ok := cheapestCrew(loaders, task.RemainingWeight, rules, func(loader *models.Loader) bool {
+		return rules.Eligible(task, loader) == nil
+	})
+	if !ok || cost > capital {
+		return nil, nil, false
+	}
+
+	next := make([]models.Loader, len(loaders))
+	copy(next, loaders)
+	loaderIDs := make([]uuid.UUID, 0, len(crew))
+	for _, i := range crew {
+		rules.ApplyFatigue(&next[i])
+		loaderIDs = append(loaderIDs, next[i].LoaderID)
+	}
+
+	return &models.PlanAssignment{TaskID: task.TaskID, LoaderIDs: loaderIDs, Cost: cost}, next, true
+}
+
+// cheapestCrew находит набор грузчиков с минимальной суммарной оплатой, суммарная
+// грузоподъемность которых не меньше weight (задача о рюкзаке с покрытием), среди
+// грузчиков, для которых allowed возвращает true. Грузоподъемность и оплата считаются
+// по правилам игры. Возвращает индексы выбранных грузчиков.
+func cheapestCrew(loaders []models.Loader, weight int, rules rulesService.Rules, allowed func(loader *models.Loader) bool) ([]int, int, bool) {
+	if weight <= 0 {
+		weight = 1
+	}
+	const inf = math.MaxInt
+
+	// cost[i][w] - минимальная стоимость набрать грузоподъемность w (не больше weight)
+	// первыми i грузчиками; from[i][w] - откуда пришли, если i-й грузчик взят, иначе -1.
+	// Строки обеих таблиц нарезаны из одного массива.
+	n := len(loaders)
+	cells := make([]int, 2*(n+1)*(weight+1))
+	cost := make([][]int, n+1)
+	from := make([][]int, n+1)
+	for i := range cost {
+		cost[i], cells = cells[:weight+1:weight+1], cells[weight+1:]
+		from[i], cells = cells[:weight+1:weight+1], cells[weight+1:]
+		for w := range cost[i] {
+			cost[i][w] = inf
+			from[i][w] = -1
+		}
+	}
+	cost[0][0] = 0
+
+	for i := 0; i < n; i++ {
+		copy(cost[i+1], cost[i])
+		capacity := rules.Capacity(&loaders[i])
+		if capacity == 0 || !allowed(&loaders[i]) {
+			continue
+		}
+		for w := 0; w <= weight; w++ {
+			if cost[i][w] == inf {
+				continue
+			}
+			nw := w + capacity
+			if nw > weight {
+				nw = weight
+			}
+			if c := cost[i][w] + rules.Cost(&loaders[i], loaders[i].Salary); c < cost[i+1][nw] {
+				cost[i+1][nw] = c
+				from[i+1][nw] = w
+			}
+		}
+	}
+
+	if cost[n][weight] == inf {
+		return nil, 0, false
+	}
+
+	var crew []int
+	w := weight
+	for i := n; i > 0; i-- {
+		if from[i][w] >= 0 {
+			crew = append(crew, i-1)
+			w = from[i][w]
+		}
+	}
+	return crew, cost[n][weight], true
+}
@@ -0,0 +1,54 @@
+package taskService
+
+import (
+	"context"
+	"errors"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/internal/service/pricingService"
+	"time"
+)
+
+type pricer interface {
+	Quote(in *pricingService.Input) int
+}
+
+// quoter котирует грузчиков по одному снимку спроса и времени.
+type quoter struct {
+	pricer pricer
+	demand float64
+	at     time.Time
+}
+
+func (s *TaskService) newQuoter(ctx context.Context) (*quoter, error) {
+	demand, err := s.loaderRepository.GetDemand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &quoter{pricer: s.pricer, demand: demand, at: s.clock.Now()}, nil
+}
+
+func (q *quoter) quote(loader *models.Loader) int {
+	return q.pricer.Quote(&pricingService.Input{Loader: loader, Demand: q.demand, At: q.at})
+}
+
+// GetQuotes возвращает текущие рыночные цены всех грузчиков за рейс.
+func (s *TaskService) GetQuotes(ctx context.Context, user *models.User) ([]models.Quote, error) {
+	if user.UserType != "customer" {
+		return nil, errors.New("not customer")
+	}
+
+	loaders, err := s.loaderRepository.GetLoaders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q, err := s.newQuoter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := make([]models.Quote, 0, len(loaders))
+	for i := range loaders {
+		quotes = append(quotes, models.Quote{LoaderID: loaders[i].LoaderID, Price: q.quote(&loaders[i])})
+	}
+	return quotes, nil
+}
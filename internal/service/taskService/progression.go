@@ -0,0 +1,18 @@
+package taskService
+
+import (
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/models"
+)
+
+// gainExperience начисляет грузчику опыт за выполненную задачу и применяет
+// прибавки всех уровней, порог которых он при этом перешел.
+func gainExperience(progression *config.Progression, loader *models.Loader) {
+	loader.Experience += progression.ExperiencePerTask
+	for loader.Level < len(progression.Levels) && loader.Experience >= progression.Levels[loader.Level].Experience {
+		level := progression.Levels[loader.Level]
+		loader.MaxWeight += level.MaxWeightBonus
+		loader.Salary += level.SalaryBonus
+		loader.Level++
+	}
+}
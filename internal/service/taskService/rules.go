@@ -0,0 +1,27 @@
+package taskService
+
+import (
+	"fmt"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/internal/service/rulesService"
+)
+
+type rulesRegistry interface {
+	Get(name string) (rulesService.Rules, error)
+}
+
+// rulesFor возвращает набор правил, выбранный заказчиком для своей игры.
+func (s *TaskService) rulesFor(customer *models.Customer) (rulesService.Rules, error) {
+	return s.rules.Get(customer.Ruleset)
+}
+
+// checkEligible проверяет, что все грузчики допущены к задаче.
+func checkEligible(rules rulesService.Rules, task *models.Task, loaders []models.Loader) error {
+	for i := range loaders {
+		err := rules.Eligible(task, &loaders[i])
+		if err != nil {
+			return reject(reasonNotEligible, fmt.Errorf("loader %s: %w", loaders[i].LoaderID, err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package taskService_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/internal/repository"
+	"github.com/AhegaoHD/WBT/internal/repository/memoryRepository"
+	"github.com/AhegaoHD/WBT/internal/service/pricingService"
+	"github.com/AhegaoHD/WBT/internal/service/rulesService"
+	"github.com/AhegaoHD/WBT/internal/service/taskService"
+	"github.com/AhegaoHD/WBT/pkg/clock"
+	"github.com/AhegaoHD/WBT/pkg/retry"
+	"github.com/google/uuid"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	stressWorkers = 16
+	stressCalls   = 100
+	stressLoaders = 8
+	stressTasks   = 12
+	stressRate    = 100
+	stressCapital = 3000
+)
+
+// invariants собирает нарушения, замеченные при записи в хранилище.
+type invariants struct {
+	mu         sync.Mutex
+	violations []string
+}
+
+func (v *invariants) fail(format string, args ...any) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.violations = append(v.violations, fmt.Sprintf(format, args...))
+}
+
+// stressCall - запись об одном вызове StartTask: какую задачу он завершил.
+type stressCall struct {
+	completed uuid.UUID
+}
+
+type stressCallKey struct{}
+
+// checkedTasks запоминает, какую задачу завершил вызов StartTask, в записи из ctx.
+type checkedTasks struct {
+	repository.TaskRepository
+}
+
+func (r checkedTasks) GetTaskByIDForUpdate(ctx context.Context, taskID uuid.UUID) (*models.Task, error) {
+	// Каждая попытка транзакции начинается с блокировки задачи - итог прошлой попытки откатан
+	if call, ok := ctx.Value(stressCallKey{}).(*stressCall); ok {
+		call.completed = uuid.Nil
+	}
+	return r.TaskRepository.GetTaskByIDForUpdate(ctx, taskID)
+}
+
+func (r checkedTasks) UpdateTask(ctx context.Context, task *models.Task) error {
+	if call, ok := ctx.Value(stressCallKey{}).(*stressCall); ok && task.Status {
+		call.completed = task.TaskID
+	}
+	return r.TaskRepository.UpdateTask(ctx, task)
+}
+
+// checkedCustomers проверяет, что капитал не уходит в минус ни в одной записи.
+type checkedCustomers struct {
+	repository.CustomerRepository
+	invariants *invariants
+}
+
+func (r checkedCustomers) UpdateCustomer(ctx context.Context, customer *models.Customer) error {
+	if customer.Capital < 0 {
+		r.invariants.fail("customer %s: capital %d written", customer.CustomerID, customer.Capital)
+	}
+	return r.CustomerRepository.UpdateCustomer(ctx, customer)
+}
+
+// checkedLoaders проверяет, что усталость остается в пределах 0..100 ни в одной записи.
+type checkedLoaders struct {
+	repository.LoaderRepository
+	invariants *invariants
+}
+
+func (r checkedLoaders) UpdateLoaders(ctx context.Context, loaders []models.Loader) error {
+	for _, loader := range loaders {
+		if loader.Fatigue < 0 || loader.Fatigue > 100 {
+			r.invariants.fail("loader %s: fatigue %d written", loader.LoaderID, loader.Fatigue)
+		}
+	}
+	return r.LoaderRepository.UpdateLoaders(ctx, loaders)
+}
+
+// stressTrip - успешный рейс: задача и бригада.
+type stressTrip struct {
+	taskID uuid.UUID
+	crew   []uuid.UUID
+}
+
+// TestStartTaskConcurrentInvariants запускает параллельные StartTask одного заказчика по общим
+// задачам и грузчикам и проверяет, что капитал не отрицательный, каждая задача завершена не
+// больше одного раза, усталость в пределах 0..100, а списанный капитал равен сумме сохраненных
+// цен грузчиков за успешные рейсы и штрафов за порчу груза.
+func TestStartTaskConcurrentInvariants(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+	for _, chargeTo := range []string{config.ChargeToCustomer, config.ChargeToLoader} {
+		t.Run(chargeTo, func(t *testing.T) {
+			damage := config.Damage{DrunkProbability: 0.5, FatigueThreshold: 60, FatigueProbability: 0.3, Penalty: 70, ChargeTo: chargeTo}
+			stressStartTask(t, damage)
+		})
+	}
+}
+
+func stressStartTask(t *testing.T, damage config.Damage) {
+	store := memoryRepository.NewStore()
+	store.SetTxRetry(retry.Policy{Retries: 3, Delay: time.Millisecond})
+	repos := memoryRepository.NewRepositories(store)
+	check := &invariants{}
+	service := newCheckedTaskService(t, repos, check, damage)
+
+	f := &fixture{repos: repos, service: service, customer: &models.User{UserID: uuid.New(), UserType: "customer"}}
+	err := repos.Customers.CreateCustomer(context.Background(), &models.Customer{CustomerID: f.customer.UserID, Capital: stressCapital, Ruleset: rulesService.RulesetDefault})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaderIDs := make([]uuid.UUID, 0, stressLoaders)
+	for i := 0; i < stressLoaders; i++ {
+		loaderIDs = append(loaderIDs, f.addLoader(t, models.Loader{MaxWeight: 10 + i*3, Drunk: i%3 == 0, Salary: stressRate}, stressRate))
+	}
+	taskIDs := make([]uuid.UUID, 0, stressTasks)
+	for i := 0; i < stressTasks; i++ {
+		taskIDs = append(taskIDs, f.addTask(t, 20+i*5, ""))
+	}
+
+	var (
+		mu          sync.Mutex
+		trips       []stressTrip
+		completions = make(map[uuid.UUID]int)
+		wg          sync.WaitGroup
+	)
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for i := 0; i < stressCalls; i++ {
+				// Бригада в случайном порядке: порядок блокировок задает репозиторий
+				crew := make([]uuid.UUID, 1+rnd.Intn(3))
+				for j, k := range rnd.Perm(len(loaderIDs))[:len(crew)] {
+					crew[j] = loaderIDs[k]
+				}
+				req := &models.StartTaskRequest{User: f.customer, TaskID: taskIDs[rnd.Intn(len(taskIDs))], LoaderIDs: crew}
+
+				call := &stressCall{}
+				err := service.StartTask(context.WithValue(context.Background(), stressCallKey{}, call), req)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				trips = append(trips, stressTrip{taskID: req.TaskID, crew: crew})
+				if call.completed != uuid.Nil {
+					completions[call.completed]++
+				}
+				mu.Unlock()
+			}
+		}(int64(w + 1))
+	}
+	wg.Wait()
+
+	for _, violation := range check.violations {
+		t.Error(violation)
+	}
+	if len(trips) == 0 {
+		t.Fatal("no StartTask call succeeded")
+	}
+
+	capital := f.capital(t)
+	if capital < 0 {
+		t.Errorf("capital = %d, want >= 0", capital)
+	}
+	// Рейс оплачивается по ценам, сохраненным в task_loaders при первом назначении
+	prices := make(map[uuid.UUID]map[uuid.UUID]int, len(taskIDs))
+	for _, taskID := range taskIDs {
+		prices[taskID], err = repos.Tasks.GetTaskLoaderPrices(context.Background(), taskID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var paid int
+	for _, trip := range trips {
+		for _, loaderID := range trip.crew {
+			price, ok := prices[trip.taskID][loaderID]
+			if !ok {
+				t.Fatalf("task %s: no stored price for loader %s", trip.taskID, loaderID)
+			}
+			paid += price
+		}
+	}
+	damages, err := repos.Tasks.GetDamagesCustomers(context.Background(), f.customer.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	penalties := make(map[uuid.UUID]int)
+	for _, event := range damages {
+		penalties[event.TaskID] += event.Penalty
+		switch event.ChargedTo {
+		case config.ChargeToCustomer:
+			paid += event.Penalty
+		case config.ChargeToLoader:
+			paid -= event.Penalty
+		}
+	}
+	if len(damages) == 0 {
+		t.Error("no damage events, settlement was not exercised")
+	}
+	if stressCapital-capital != paid {
+		t.Errorf("capital spent = %d, stored prices and penalties add up to %d", stressCapital-capital, paid)
+	}
+
+	for _, taskID := range taskIDs {
+		task := f.task(t, taskID)
+		switch n := completions[taskID]; {
+		case n > 1:
+			t.Errorf("task %s completed %d times", taskID, n)
+		case n == 1 && (!task.Status || task.RemainingWeight != 0):
+			t.Errorf("task %s completed but stored as %+v", taskID, task)
+		case n == 0 && task.Status:
+			t.Errorf("task %s stored as completed without a completing trip", taskID)
+		}
+		if task.DamagePenalty != penalties[taskID] {
+			t.Errorf("task %s: damage penalty = %d, damage events add up to %d", taskID, task.DamagePenalty, penalties[taskID])
+		}
+		if task.RemainingWeight < 0 || task.RemainingWeight > task.Weight {
+			t.Errorf("task %s: remaining weight %d of %d", taskID, task.RemainingWeight, task.Weight)
+		}
+	}
+
+	for _, loaderID := range loaderIDs {
+		if loader := f.loader(t, loaderID); loader.Fatigue < 0 || loader.Fatigue > 100 {
+			t.Errorf("loader %s: fatigue = %d, want 0..100", loaderID, loader.Fatigue)
+		}
+	}
+	t.Logf("%d successful trips, %d tasks completed, %d damage events, capital left %d", len(trips), len(completions), len(damages), capital)
+}
+
+// newCheckedTaskService создает TaskService поверх repos, проверяющий инварианты при каждой записи.
+func newCheckedTaskService(t *testing.T, repos *repository.Repositories, check *invariants, damage config.Damage) *taskService.TaskService {
+	t.Helper()
+	cfg := &config.Config{Game: config.DefaultGame(), CargoTypes: testCargoTypes(), Damage: damage}
+	rules, err := rulesService.NewRegistry(cfg.Game, cfg.CargoTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return taskService.NewTaskService(repos.Tx, checkedTasks{repos.Tasks}, checkedLoaders{repos.Loaders, check}, checkedCustomers{repos.Customers, check},
+		repos.Contracts, repos.Applications, rules, cfg.Damage, cfg.Progression, 1, pricingService.FlatPricer{},
+		clock.NewFake(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)))
+}
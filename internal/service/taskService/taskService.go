@@ -3,121 +3,378 @@ package taskService
 import (
 	"context"
 	"errors"
-	"fmt"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/metrics"
 	"github.com/AhegaoHD/WBT/internal/models"
-	"github.com/AhegaoHD/WBT/pkg/postgres"
+	"github.com/AhegaoHD/WBT/internal/service/rulesService"
+	"github.com/AhegaoHD/WBT/pkg/clock"
+	"github.com/AhegaoHD/WBT/pkg/logger"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
+	"sort"
 )
 
 type TaskService struct {
-	db                 *postgres.Postgres
-	taskRepository     taskRepository
-	loaderRepository   loaderRepository
-	customerRepository customerRepository
+	tx                    txManager
+	taskRepository        taskRepository
+	loaderRepository      loaderRepository
+	customerRepository    customerRepository
+	contractRepository    contractRepository
+	applicationRepository applicationRepository
+	rules                 rulesRegistry
+	damage                config.Damage
+	progression           config.Progression
+	damageSeed            int64
+	pricer                pricer
+	clock                 clock.Clock
+}
+
+type txManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type customerRepository interface {
-	GetCustomerByIDForUpdate(ctx context.Context, customerID uuid.UUID, tx pgx.Tx) (*models.Customer, error)
-	UpdateCustomer(ctx context.Context, customer *models.Customer, tx pgx.Tx) error
+	GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error)
+	GetCustomerByIDForUpdate(ctx context.Context, customerID uuid.UUID) (*models.Customer, error)
+	UpdateCustomer(ctx context.Context, customer *models.Customer) error
 }
 
 type loaderRepository interface {
-	GetLoadersByIDsForUpdate(ctx context.Context, loaderIDs []uuid.UUID, tx pgx.Tx) ([]models.Loader, error)
-	UpdateLoaders(ctx context.Context, loaders []models.Loader, tx pgx.Tx) error
+	GetLoaders(ctx context.Context) ([]models.Loader, error)
+	GetDemand(ctx context.Context) (float64, error)
+	GetLoadersByIDsForUpdate(ctx context.Context, loaderIDs []uuid.UUID) ([]models.Loader, error)
+	LockLoaders(ctx context.Context, loaderIDs []uuid.UUID) error
+	UpdateLoaders(ctx context.Context, loaders []models.Loader) error
+}
+
+type contractRepository interface {
+	GetActiveContracts(ctx context.Context, customerID uuid.UUID, loaderIDs []uuid.UUID) ([]models.Contract, error)
+	GetRoster(ctx context.Context, customerID uuid.UUID) ([]models.RosterEntry, error)
+}
+
+type applicationRepository interface {
+	CreateApplication(ctx context.Context, application *models.Application) (*models.Application, error)
+	GetApplicationsCustomers(ctx context.Context, customerID uuid.UUID, taskID uuid.UUID) ([]models.Application, error)
+	GetApplicationsLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Application, error)
+	GetApplicationsByIDsForUpdate(ctx context.Context, applicationIDs []uuid.UUID) ([]models.Application, error)
+	CloseApplications(ctx context.Context, taskID uuid.UUID, acceptedIDs []uuid.UUID, rejectRest bool) error
 }
 
 type taskRepository interface {
-	GetTaskByIDForUpdate(ctx context.Context, taskID uuid.UUID, tx pgx.Tx) (*models.Task, error)
-	UpdateTask(ctx context.Context, task *models.Task, tx pgx.Tx) error
-	CreateTaskLoaders(ctx context.Context, taskID uuid.UUID, loaderIDs []uuid.UUID, tx pgx.Tx) error
+	GetPublishedTasks(ctx context.Context) ([]models.Task, error)
+	GetTaskQueue(ctx context.Context, customerID uuid.UUID) ([]models.Task, error)
+	GetTaskByID(ctx context.Context, taskID uuid.UUID) (*models.Task, error)
+	GetTaskByIDForUpdate(ctx context.Context, taskID uuid.UUID) (*models.Task, error)
+	LockTasks(ctx context.Context, taskIDs []uuid.UUID) error
+	UpdateTask(ctx context.Context, task *models.Task) error
+	CreateTaskLoaders(ctx context.Context, taskLoaders []models.TaskLoader) error
+	GetTaskLoaderPrices(ctx context.Context, taskID uuid.UUID) (map[uuid.UUID]int, error)
+	CreateDamageEvents(ctx context.Context, damages []models.DamageEvent) error
+	GetDamagesCustomers(ctx context.Context, customerID uuid.UUID) ([]models.DamageEvent, error)
 }
 
-func NewTaskService(db *postgres.Postgres, taskRepository taskRepository, loaderRepository loaderRepository, customerRepository customerRepository) *TaskService {
-	return &TaskService{db: db, taskRepository: taskRepository, loaderRepository: loaderRepository, customerRepository: customerRepository}
+func NewTaskService(tx txManager, taskRepository taskRepository, loaderRepository loaderRepository, customerRepository customerRepository, contractRepository contractRepository, applicationRepository applicationRepository, rules rulesRegistry, damage config.Damage, progression config.Progression, damageSeed int64, pricer pricer, clock clock.Clock) *TaskService {
+	return &TaskService{tx: tx, taskRepository: taskRepository, loaderRepository: loaderRepository, customerRepository: customerRepository, contractRepository: contractRepository, applicationRepository: applicationRepository, rules: rules, damage: damage, progression: progression, damageSeed: damageSeed, pricer: pricer, clock: clock}
 }
 
 func (s *TaskService) StartTask(ctx context.Context, req *models.StartTaskRequest) error {
-	tx, err := s.db.Pool.Begin(ctx)
+	var spent int
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		spent, err = s.startTask(ctx, req)
+		return err
+	})
+	l := logger.FromContext(ctx).With("task_id", req.TaskID, "loaders", len(req.LoaderIDs))
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		metrics.TasksFailed.WithLabelValues(failureReason(err)).Inc()
+		l.Debug("task not started: %v", err)
+		return err
 	}
-	defer tx.Rollback(ctx)
+	recordTrips(1, spent)
+	l.Info("task started")
+	return nil
+}
 
+// startTask выполняет все проверки и изменения StartTask внутри транзакции из ctx
+// и возвращает капитал, выплаченный бригаде за рейс.
+func (s *TaskService) startTask(ctx context.Context, req *models.StartTaskRequest) (int, error) {
 	//валидация
 	if req.User.UserType != "customer" {
-		return errors.New("not customer")
+		return 0, reject(reasonNotCustomer, errors.New("not customer"))
 	}
 
-	task, err := s.taskRepository.GetTaskByIDForUpdate(ctx, req.TaskID, tx)
+	task, err := s.taskRepository.GetTaskByIDForUpdate(ctx, req.TaskID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if task.Status {
-		return errors.New("уже выполнена")
+		return 0, reject(reasonCompleted, errors.New("уже выполнена"))
 	}
 	if task.CustomerID != req.User.UserID {
-		return errors.New("task.CustomerID != req.User.UserID")
+		return 0, reject(reasonNotOwner, errors.New("task.CustomerID != req.User.UserID"))
+	}
+	if req.Version != nil && *req.Version != task.Version {
+		return 0, models.ErrVersionMismatch
 	}
-	customer, err := s.customerRepository.GetCustomerByIDForUpdate(ctx, req.User.UserID, tx)
+	customer, err := s.customerRepository.GetCustomerByIDForUpdate(ctx, req.User.UserID)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	loaders, err := s.loaderRepository.GetLoadersByIDsForUpdate(ctx, req.LoaderIDs, tx)
+	loaders, err := s.loaderRepository.GetLoadersByIDsForUpdate(ctx, req.LoaderIDs)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	rules, err := s.rulesFor(customer)
+	if err != nil {
+		return 0, err
+	}
+	err = checkEligible(rules, task, loaders)
+	if err != nil {
+		return 0, err
 	}
+	pay, err := s.contractPay(ctx, task, loaders)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.runTrip(ctx, rules, task, customer, loaders, pay)
+}
 
+// runTrip выполняет один рейс бригады по задаче: проверяет грузоподъемность и капитал,
+// начисляет усталость, оплату, штрафы и опыт и сохраняет изменения. Задача, заказчик
+// и грузчики должны быть заблокированы вызывающим, pay - согласованная цена каждого
+// грузчика за рейс; сколько из нее платится фактически, решают правила игры.
+// Возвращает капитал, выплаченный бригаде: оплату рейса без штрафов, удержанных из нее.
+// Штрафы, списанные с заказчика, сюда не входят.
+func (s *TaskService) runTrip(ctx context.Context, rules rulesService.Rules, task *models.Task, customer *models.Customer, loaders []models.Loader, pay map[uuid.UUID]int) (int, error) {
 	var sumWeightLoaders int
 	var sumSalaryLoaders int
+	var damages []models.DamageEvent
+	cost := make(map[uuid.UUID]int, len(loaders))
+	rnd := s.tripRandom(task)
+	// carried - грузчики, которые что-то перенесли в этом рейсе
+	carried := make([]int, 0, len(loaders))
 	for i := range loaders {
-		sumSalaryLoaders += loaders[i].Salary
+		cost[loaders[i].LoaderID] = rules.Cost(&loaders[i], pay[loaders[i].LoaderID])
+		sumSalaryLoaders += cost[loaders[i].LoaderID]
 		if loaders[i].Fatigue == 100 {
 			continue
 		}
-		sumWeightLoaders += loaders[i].MaxWeight * (100 - loaders[i].Fatigue) / 100
-		if loaders[i].Drunk {
-			loaders[i].Fatigue += 50
-		} else {
-			loaders[i].Fatigue += 20
-		}
-		if loaders[i].Fatigue > 100 {
-			loaders[i].Fatigue = 100
+		carried = append(carried, i)
+		sumWeightLoaders += rules.Capacity(&loaders[i])
+		if damage := s.rollDamage(rnd, task, &loaders[i]); damage != nil {
+			damages = append(damages, *damage)
 		}
+		rules.ApplyFatigue(&loaders[i])
 	}
-	if sumWeightLoaders < task.Weight {
-		return errors.New("sumWeightLoaders < task.Weight")
+	if sumWeightLoaders == 0 {
+		return 0, reject(reasonNoCapacity, errors.New("sumWeightLoaders == 0"))
 	}
 	if customer.Capital < sumSalaryLoaders {
-		return errors.New(" customer.Capital < sumSalaryLoaders")
+		return 0, reject(reasonCapital, errors.New(" customer.Capital < sumSalaryLoaders"))
 	}
 
+	// Бригада переносит столько, сколько может за один рейс,
+	// задача выполнена, когда не осталось веса
 	customer.Capital -= sumSalaryLoaders
-	task.Status = true
+	if sumWeightLoaders > task.RemainingWeight {
+		sumWeightLoaders = task.RemainingWeight
+	}
+	task.RemainingWeight -= sumWeightLoaders
+	task.Status = task.RemainingWeight == 0
+	settleDamages(task, customer, cost, damages)
 
-	err = s.loaderRepository.UpdateLoaders(ctx, loaders, tx)
+	// Опыт получают грузчики, которые переносили груз в рейсе, завершившем задачу
+	if task.Status {
+		for _, i := range carried {
+			gainExperience(&s.progression, &loaders[i])
+		}
+	}
+
+	err := s.loaderRepository.UpdateLoaders(ctx, loaders)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	err = s.customerRepository.UpdateCustomer(ctx, customer, tx)
+	err = s.customerRepository.UpdateCustomer(ctx, customer)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	err = s.taskRepository.UpdateTask(ctx, task, tx)
+	err = s.taskRepository.UpdateTask(ctx, task)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	err = s.taskRepository.CreateTaskLoaders(ctx, task.TaskID, req.LoaderIDs, tx)
+	taskLoaders := make([]models.TaskLoader, 0, len(loaders))
+	for i := range loaders {
+		taskLoaders = append(taskLoaders, models.TaskLoader{TaskID: task.TaskID, LoaderID: loaders[i].LoaderID, Price: pay[loaders[i].LoaderID]})
+	}
+	err = s.taskRepository.CreateTaskLoaders(ctx, taskLoaders)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	err = tx.Commit(ctx) // Завершаем транзакцию после всех операций
+	err = s.taskRepository.CreateDamageEvents(ctx, damages)
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return 0, err
 	}
-	return nil
+	paid := sumSalaryLoaders
+	for _, damage := range damages {
+		if damage.ChargedTo == config.ChargeToLoader {
+			paid -= damage.Penalty
+		}
+	}
+	return paid, nil
+}
+
+// GetTask возвращает задачу ее заказчику, а грузчику - только опубликованную задачу.
+func (s *TaskService) GetTask(ctx context.Context, user *models.User, taskID uuid.UUID) (*models.Task, error) {
+	task, err := s.taskRepository.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	switch user.UserType {
+	case "customer":
+		if task.CustomerID != user.UserID {
+			return nil, errors.New("task.CustomerID != user.UserID")
+		}
+	case "loader":
+		if !task.Published {
+			return nil, errors.New("task is not published")
+		}
+	default:
+		return nil, errors.New("err")
+	}
+	return task, nil
+}
+
+func (s *TaskService) SetTaskPriority(ctx context.Context, req *models.SetTaskPriorityRequest) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if req.User.UserType != "customer" {
+			return errors.New("not customer")
+		}
+
+		task, err := s.taskRepository.GetTaskByIDForUpdate(ctx, req.TaskID)
+		if err != nil {
+			return err
+		}
+		if task.CustomerID != req.User.UserID {
+			return errors.New("task.CustomerID != req.User.UserID")
+		}
+		if task.Status {
+			return errors.New("уже выполнена")
+		}
+		if req.Version != nil && *req.Version != task.Version {
+			return models.ErrVersionMismatch
+		}
+
+		task.Priority = req.Priority
+		task.Deadline = req.Deadline
+
+		return s.taskRepository.UpdateTask(ctx, task)
+	})
+}
+
+func (s *TaskService) GetTaskQueue(ctx context.Context, user *models.User) ([]models.Task, error) {
+	if user.UserType != "customer" {
+		return nil, errors.New("not customer")
+	}
+	return s.taskRepository.GetTaskQueue(ctx, user.UserID)
+}
+
+func (s *TaskService) GetDamages(ctx context.Context, user *models.User) ([]models.DamageEvent, error) {
+	if user.UserType != "customer" {
+		return nil, errors.New("not customer")
+	}
+	return s.taskRepository.GetDamagesCustomers(ctx, user.UserID)
+}
+
+// AutoDispatch проходит по очереди задач заказчика и запускает каждую задачу,
+// для которой удается собрать бригаду из текущих грузчиков и хватает капитала.
+// Задачу, которую штат не может закончить за один рейс, бригада начинает частичным рейсом.
+// Каждая задача запускается через StartTask отдельной транзакцией, поэтому
+// все проверки StartTask сохраняются, а неудача одной задачи не отменяет остальные.
+func (s *TaskService) AutoDispatch(ctx context.Context, user *models.User) (*models.DispatchResult, error) {
+	queue, err := s.GetTaskQueue(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.DispatchResult{
+		Started: []uuid.UUID{},
+		Skipped: []models.DispatchSkip{},
+	}
+	for _, task := range queue {
+		loaderIDs, err := s.pickCrew(ctx, user.UserID, &task)
+		if err == nil {
+			err = s.StartTask(ctx, &models.StartTaskRequest{User: user, TaskID: task.TaskID, LoaderIDs: loaderIDs})
+		}
+		if err != nil {
+			result.Skipped = append(result.Skipped, models.DispatchSkip{TaskID: task.TaskID, Reason: err.Error()})
+			continue
+		}
+		result.Started = append(result.Started, task.TaskID)
+	}
+
+	return result, nil
+}
+
+// pickCrew жадно набирает из штата заказчика допущенных к грузу грузчиков с наибольшей текущей
+// грузоподъемностью, пока их суммарная грузоподъемность не покроет оставшийся вес задачи.
+// Если всего штата не хватает на оставшийся вес, набирается бригада частичного рейса.
+func (s *TaskService) pickCrew(ctx context.Context, customerID uuid.UUID, task *models.Task) ([]uuid.UUID, error) {
+	customer, err := s.customerRepository.GetCustomerByID(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.rulesFor(customer)
+	if err != nil {
+		return nil, err
+	}
+	loaders, err := s.rosterLoaders(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(loaders, func(i, j int) bool {
+		return rules.Capacity(&loaders[i]) > rules.Capacity(&loaders[j])
+	})
+
+	weight := task.RemainingWeight
+	var loaderIDs []uuid.UUID
+	var sumWeight, sumSalary int
+	for i := range loaders {
+		if sumWeight >= weight {
+			break
+		}
+		capacity := rules.Capacity(&loaders[i])
+		if capacity == 0 {
+			break
+		}
+		if rules.Eligible(task, &loaders[i]) != nil {
+			continue
+		}
+		loaderIDs = append(loaderIDs, loaders[i].LoaderID)
+		sumWeight += capacity
+		sumSalary += rules.Cost(&loaders[i], loaders[i].Salary)
+	}
+	if sumWeight == 0 {
+		return nil, errors.New("not enough loaders")
+	}
+	if sumWeight < weight {
+		crew, _, ok := partialCrew(task, loaders, customer.Capital, rules)
+		if !ok {
+			return nil, errors.New("not enough capital")
+		}
+		loaderIDs = loaderIDs[:0]
+		for _, i := range crew {
+			loaderIDs = append(loaderIDs, loaders[i].LoaderID)
+		}
+		return loaderIDs, nil
+	}
+	if customer.Capital < sumSalary {
+		return nil, errors.New("not enough capital")
+	}
+
+	return loaderIDs, nil
 }
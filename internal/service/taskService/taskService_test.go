@@ -0,0 +1,617 @@
+package taskService_test
+
+import (
+	"context"
+	"errors"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/metrics"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/internal/repository"
+	"github.com/AhegaoHD/WBT/internal/repository/memoryRepository"
+	"github.com/AhegaoHD/WBT/internal/service/pricingService"
+	"github.com/AhegaoHD/WBT/internal/service/rulesService"
+	"github.com/AhegaoHD/WBT/internal/service/taskService"
+	"github.com/AhegaoHD/WBT/pkg/clock"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"runtime"
+	"testing"
+	"time"
+)
+
+type fixture struct {
+	repos    *repository.Repositories
+	service  *taskService.TaskService
+	customer *models.User
+}
+
+// newFixture создает заказчика с капиталом capital и набором правил ruleset.
+// Порча груза выключена, цена на рынке равна Salary грузчика.
+func newFixture(t *testing.T, capital int, ruleset string) *fixture {
+	t.Helper()
+	return newFixtureConfig(t, &config.Config{Game: config.DefaultGame(), CargoTypes: testCargoTypes()}, capital, ruleset)
+}
+
+// newFixtureConfig - newFixture с порчей груза, опытом и остальными правилами из cfg.
+func newFixtureConfig(t *testing.T, cfg *config.Config, capital int, ruleset string) *fixture {
+	t.Helper()
+	rules, err := rulesService.NewRegistry(cfg.Game, cfg.CargoTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repos := memoryRepository.NewRepositories(memoryRepository.NewStore())
+	service := taskService.NewTaskService(repos.Tx, repos.Tasks, repos.Loaders, repos.Customers, repos.Contracts, repos.Applications,
+		rules, cfg.Damage, cfg.Progression, 1, pricingService.FlatPricer{}, clock.NewFake(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)))
+
+	f := &fixture{repos: repos, service: service, customer: &models.User{UserID: uuid.New(), UserType: "customer"}}
+	err = repos.Customers.CreateCustomer(context.Background(), &models.Customer{CustomerID: f.customer.UserID, Capital: capital, Ruleset: ruleset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func testCargoTypes() []config.CargoType {
+	return []config.CargoType{{Name: "fragile", ForbidDrunk: true}}
+}
+
+// addLoader создает грузчика и действующий договор с оплатой rate за рейс (0 - рыночная цена).
+func (f *fixture) addLoader(t *testing.T, loader models.Loader, rate int) uuid.UUID {
+	t.Helper()
+	loaderID := f.addFreeLoader(t, loader)
+	_, err := f.repos.Contracts.CreateContract(context.Background(), &models.Contract{
+		CustomerID: f.customer.UserID,
+		LoaderID:   loaderID,
+		Kind:       models.ContractPerTask,
+		Rate:       rate,
+		Status:     models.ContractActive,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaderID
+}
+
+// addFreeLoader создает грузчика без договора с заказчиком.
+func (f *fixture) addFreeLoader(t *testing.T, loader models.Loader) uuid.UUID {
+	t.Helper()
+	loader.LoaderID = uuid.New()
+	err := f.repos.Loaders.CreateLoader(context.Background(), &loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loader.LoaderID
+}
+
+func (f *fixture) addTask(t *testing.T, weight int, cargoType string) uuid.UUID {
+	t.Helper()
+	before := f.tasks(t)
+	err := f.repos.Tasks.CreateTasks(context.Background(), []models.Task{{CustomerID: f.customer.UserID, Weight: weight, RemainingWeight: weight, CargoType: cargoType}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := f.tasks(t)
+	if len(after) != len(before)+1 {
+		t.Fatalf("task was not created")
+	}
+	return after[len(after)-1].TaskID
+}
+
+func (f *fixture) tasks(t *testing.T) []models.Task {
+	t.Helper()
+	tasks, err := f.repos.Tasks.GetTasksCustomers(context.Background(), f.customer.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tasks
+}
+
+func (f *fixture) task(t *testing.T, taskID uuid.UUID) models.Task {
+	t.Helper()
+	task, err := f.repos.Tasks.GetTaskByIDForUpdate(context.Background(), taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *task
+}
+
+func (f *fixture) capital(t *testing.T) int {
+	t.Helper()
+	customer, err := f.repos.Customers.GetCustomerByID(context.Background(), f.customer.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return customer.Capital
+}
+
+func (f *fixture) loader(t *testing.T, loaderID uuid.UUID) models.Loader {
+	t.Helper()
+	loader, err := f.repos.Loaders.GetLoaderByID(context.Background(), loaderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *loader
+}
+
+func TestStartTaskCompletesTask(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	loaderID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+	taskID := f.addTask(t, 20, "")
+
+	err := f.service.StartTask(context.Background(), &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := f.task(t, taskID)
+	if !task.Status || task.RemainingWeight != 0 {
+		t.Errorf("task = %+v, want completed", task)
+	}
+	if capital := f.capital(t); capital != 4000 {
+		t.Errorf("capital = %d, want 4000", capital)
+	}
+	if loader := f.loader(t, loaderID); loader.Fatigue != 20 {
+		t.Errorf("fatigue = %d, want 20", loader.Fatigue)
+	}
+}
+
+func TestStartTaskPartialTrips(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	loaderID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+	taskID := f.addTask(t, 50, "")
+	req := &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}}
+
+	err := f.service.StartTask(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := f.task(t, taskID); task.Status || task.RemainingWeight != 20 {
+		t.Fatalf("after first trip task = %+v, want 20 remaining", task)
+	}
+
+	// Уставший на 20% грузчик переносит 24, этого хватает на остаток
+	err = f.service.StartTask(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := f.task(t, taskID); !task.Status {
+		t.Errorf("after second trip task = %+v, want completed", task)
+	}
+	if capital := f.capital(t); capital != 3000 {
+		t.Errorf("capital = %d, want 3000", capital)
+	}
+}
+
+func TestStartTaskRollsBackOnError(t *testing.T) {
+	f := newFixture(t, 500, rulesService.RulesetDefault)
+	loaderID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+	taskID := f.addTask(t, 20, "")
+
+	err := f.service.StartTask(context.Background(), &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}})
+	if err == nil {
+		t.Fatal("expected error for insufficient capital")
+	}
+
+	if task := f.task(t, taskID); task.Status || task.RemainingWeight != 20 {
+		t.Errorf("task = %+v, want untouched", task)
+	}
+	if capital := f.capital(t); capital != 500 {
+		t.Errorf("capital = %d, want 500", capital)
+	}
+	if loader := f.loader(t, loaderID); loader.Fatigue != 0 {
+		t.Errorf("fatigue = %d, want 0", loader.Fatigue)
+	}
+}
+
+func TestStartTaskRejectsLoaderWithoutContract(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	loaderID := f.addFreeLoader(t, models.Loader{MaxWeight: 30, Salary: 1000})
+	taskID := f.addTask(t, 20, "")
+
+	err := f.service.StartTask(context.Background(), &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}})
+	if err == nil {
+		t.Fatal("expected error for loader without contract")
+	}
+	if capital := f.capital(t); capital != 5000 {
+		t.Errorf("capital = %d, want 5000", capital)
+	}
+}
+
+func TestStartTaskChecksCargoType(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	loaderID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000, Drunk: true}, 1000)
+	taskID := f.addTask(t, 20, "fragile")
+
+	err := f.service.StartTask(context.Background(), &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}})
+	if err == nil {
+		t.Fatal("expected error for drunk loader on fragile cargo")
+	}
+}
+
+func TestStartTaskMarketPriceIsLockedForTask(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	loaderID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 700}, 0)
+	taskID := f.addTask(t, 50, "")
+	req := &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}}
+
+	for i := 0; i < 2; i++ {
+		err := f.service.StartTask(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if capital := f.capital(t); capital != 3600 {
+		t.Errorf("capital = %d, want 3600", capital)
+	}
+}
+
+func TestStartTaskZeroPriceDoesNotLockMarketPrice(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	loaderID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 700}, 0)
+	taskID := f.addTask(t, 50, "")
+	// Рейс по абонементу или строка до появления цен
+	err := f.repos.Tasks.CreateTaskLoaders(context.Background(), []models.TaskLoader{{TaskID: taskID, LoaderID: loaderID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}}
+	for i := 0; i < 2; i++ {
+		err = f.service.StartTask(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if capital := f.capital(t); capital != 3600 {
+		t.Errorf("capital = %d, want 3600", capital)
+	}
+}
+
+func TestStartTaskExperienceOnlyForCarriers(t *testing.T) {
+	cfg := &config.Config{Game: config.DefaultGame(), CargoTypes: testCargoTypes(), Progression: config.Progression{ExperiencePerTask: 10}}
+	f := newFixtureConfig(t, cfg, 5000, rulesService.RulesetDefault)
+	carrierID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+	exhaustedID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000, Fatigue: 100}, 1000)
+	taskID := f.addTask(t, 20, "")
+
+	err := f.service.StartTask(context.Background(), &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{carrierID, exhaustedID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := f.task(t, taskID); !task.Status {
+		t.Fatalf("task = %+v, want completed", task)
+	}
+	if loader := f.loader(t, carrierID); loader.Experience != 10 {
+		t.Errorf("carrier experience = %d, want 10", loader.Experience)
+	}
+	if loader := f.loader(t, exhaustedID); loader.Experience != 0 {
+		t.Errorf("exhausted loader experience = %d, want 0", loader.Experience)
+	}
+}
+
+func TestPieceworkPaysForCapacity(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetPiecework)
+	loaderID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000, Fatigue: 50}, 1000)
+	taskID := f.addTask(t, 10, "")
+
+	err := f.service.StartTask(context.Background(), &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capital := f.capital(t); capital != 4500 {
+		t.Errorf("capital = %d, want 4500", capital)
+	}
+}
+
+func TestStartTasksModes(t *testing.T) {
+	tests := []struct {
+		mode          string
+		wantCommitted bool
+		wantCapital   int
+	}{
+		{models.BatchModeAllOrNothing, false, 1500},
+		{models.BatchModeBestEffort, true, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			f := newFixture(t, 1500, rulesService.RulesetDefault)
+			cheap := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+			expensive := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 2000}, 2000)
+			first := f.addTask(t, 20, "")
+			second := f.addTask(t, 20, "")
+
+			result, _ := f.service.StartTasks(context.Background(), &models.BatchStartTaskRequest{
+				User: f.customer,
+				Mode: tt.mode,
+				Tasks: []models.StartTaskRequest{
+					{TaskID: first, LoaderIDs: []uuid.UUID{cheap}},
+					{TaskID: second, LoaderIDs: []uuid.UUID{expensive}},
+				},
+			})
+			if result == nil {
+				t.Fatal("no result")
+			}
+			if result.Committed != tt.wantCommitted {
+				t.Errorf("committed = %v, want %v", result.Committed, tt.wantCommitted)
+			}
+			if capital := f.capital(t); capital != tt.wantCapital {
+				t.Errorf("capital = %d, want %d", capital, tt.wantCapital)
+			}
+			if task := f.task(t, first); task.Status != tt.wantCommitted {
+				t.Errorf("first task status = %v, want %v", task.Status, tt.wantCommitted)
+			}
+		})
+	}
+}
+
+func TestPlanTasksFitsCapital(t *testing.T) {
+	f := newFixture(t, 2500, rulesService.RulesetDefault)
+	f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+	f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+	f.addTask(t, 20, "")
+	f.addTask(t, 20, "")
+	f.addTask(t, 20, "")
+
+	plan, err := f.service.PlanTasks(context.Background(), f.customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Assignments) != 2 || plan.TotalCost != 2000 || plan.CapitalLeft != 500 {
+		t.Fatalf("plan = %+v, want 2 tasks for 2000", plan)
+	}
+
+	result, err := f.service.ExecutePlan(context.Background(), f.customer, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Committed {
+		t.Error("plan was not committed")
+	}
+	if capital := f.capital(t); capital != 500 {
+		t.Errorf("capital = %d, want 500", capital)
+	}
+	if tasks := f.tasks(t); len(tasks) != 1 {
+		t.Errorf("%d tasks left, want 1", len(tasks))
+	}
+}
+
+func TestPlanTasksPlansPartialTrip(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+	f.addLoader(t, models.Loader{MaxWeight: 20, Salary: 1000}, 1000)
+	lightID := f.addTask(t, 20, "")
+	heavyID := f.addTask(t, 100, "")
+
+	plan, err := f.service.PlanTasks(context.Background(), f.customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Assignments) != 2 {
+		t.Fatalf("plan = %+v, want light task and a partial trip", plan)
+	}
+	if a := plan.Assignments[0]; a.TaskID != lightID || a.Partial {
+		t.Errorf("first assignment = %+v, want full trip for the light task", a)
+	}
+	if a := plan.Assignments[1]; a.TaskID != heavyID || !a.Partial || len(a.LoaderIDs) != 2 {
+		t.Errorf("second assignment = %+v, want partial trip of the whole roster", a)
+	}
+
+	_, err = f.service.ExecutePlan(context.Background(), f.customer, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := f.task(t, heavyID); task.Status || task.RemainingWeight >= 100 {
+		t.Errorf("heavy task = %+v, want partially carried", task)
+	}
+}
+
+func TestAutoDispatchStartsPartialTrip(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+	taskID := f.addTask(t, 100, "")
+
+	result, err := f.service.AutoDispatch(context.Background(), f.customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Started) != 1 || result.Started[0] != taskID {
+		t.Fatalf("result = %+v, want the heavy task started", result)
+	}
+	if task := f.task(t, taskID); task.RemainingWeight != 70 {
+		t.Errorf("remaining weight = %d, want 70", task.RemainingWeight)
+	}
+}
+
+func TestPlanTasksLargeRosterIsBounded(t *testing.T) {
+	f := newFixture(t, 1000000, rulesService.RulesetDefault)
+	for i := 0; i < 100; i++ {
+		f.addLoader(t, models.Loader{MaxWeight: 10 + i%20, Salary: 1000 + i}, 1000+i)
+	}
+	for i := 0; i < 7; i++ {
+		f.addTask(t, 80, "")
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	plan, err := f.service.PlanTasks(context.Background(), f.customer)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Assignments) != 7 {
+		t.Errorf("plan = %+v, want all 7 tasks", plan)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("plan allocated %d MB", allocated>>20)
+	}
+}
+
+func TestSetTaskPriorityChecksVersion(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	taskID := f.addTask(t, 20, "")
+	version := f.task(t, taskID).Version
+
+	err := f.service.SetTaskPriority(context.Background(), &models.SetTaskPriorityRequest{User: f.customer, TaskID: taskID, Priority: 5, Version: &version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := f.task(t, taskID); task.Priority != 5 || task.Version != version+1 {
+		t.Fatalf("task = %+v, want priority 5 and version %d", task, version+1)
+	}
+
+	// Второй клиент прочитал задачу до первого изменения
+	err = f.service.SetTaskPriority(context.Background(), &models.SetTaskPriorityRequest{User: f.customer, TaskID: taskID, Priority: 1, Version: &version})
+	if !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("err = %v, want %v", err, models.ErrVersionMismatch)
+	}
+	if task := f.task(t, taskID); task.Priority != 5 {
+		t.Errorf("priority = %d, want 5", task.Priority)
+	}
+}
+
+func TestStartTaskChecksVersion(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	loaderID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+	taskID := f.addTask(t, 50, "")
+	stale := f.task(t, taskID).Version - 1
+
+	err := f.service.StartTask(context.Background(), &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}, Version: &stale})
+	if !errors.Is(err, models.ErrVersionMismatch) {
+		t.Fatalf("err = %v, want %v", err, models.ErrVersionMismatch)
+	}
+	if capital := f.capital(t); capital != 5000 {
+		t.Errorf("capital = %d, want 5000", capital)
+	}
+
+	version := stale + 1
+	err = f.service.StartTask(context.Background(), &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}, Version: &version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := f.task(t, taskID); task.Version != version+1 {
+		t.Errorf("version = %d, want %d", task.Version, version+1)
+	}
+}
+
+func TestStartTaskMetrics(t *testing.T) {
+	f := newFixture(t, 1500, rulesService.RulesetDefault)
+	loaderID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 1000)
+	taskID := f.addTask(t, 50, "")
+	req := &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{loaderID}}
+
+	started := testutil.ToFloat64(metrics.TasksStarted)
+	spent := testutil.ToFloat64(metrics.CapitalSpent)
+	noCapital := testutil.ToFloat64(metrics.TasksFailed.WithLabelValues("capital"))
+	noContract := testutil.ToFloat64(metrics.TasksFailed.WithLabelValues("no_contract"))
+
+	err := f.service.StartTask(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// На второй рейс капитала не хватает
+	err = f.service.StartTask(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error for insufficient capital")
+	}
+	freeID := f.addFreeLoader(t, models.Loader{MaxWeight: 30, Salary: 1000})
+	err = f.service.StartTask(context.Background(), &models.StartTaskRequest{User: f.customer, TaskID: taskID, LoaderIDs: []uuid.UUID{freeID}})
+	if err == nil {
+		t.Fatal("expected error for loader without contract")
+	}
+
+	if got := testutil.ToFloat64(metrics.TasksStarted) - started; got != 1 {
+		t.Errorf("tasks started = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.CapitalSpent) - spent; got != 1000 {
+		t.Errorf("capital spent = %v, want 1000", got)
+	}
+	if got := testutil.ToFloat64(metrics.TasksFailed.WithLabelValues("capital")) - noCapital; got != 1 {
+		t.Errorf("failed for capital = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.TasksFailed.WithLabelValues("no_contract")) - noContract; got != 1 {
+		t.Errorf("failed for no contract = %v, want 1", got)
+	}
+}
+
+func TestConfirmCrewMetrics(t *testing.T) {
+	// Пьяный грузчик портит груз в каждом рейсе, штраф удерживается из его оплаты
+	cfg := &config.Config{Game: config.DefaultGame(), CargoTypes: testCargoTypes(),
+		Damage: config.Damage{DrunkProbability: 1, FatigueThreshold: 100, Penalty: 300, ChargeTo: config.ChargeToLoader}}
+	f := newFixtureConfig(t, cfg, 5000, rulesService.RulesetDefault)
+	loaderID := f.addFreeLoader(t, models.Loader{MaxWeight: 30, Salary: 1000, Drunk: true})
+	loader := &models.User{UserID: loaderID, UserType: "loader"}
+	taskID := f.addTask(t, 20, "")
+	unpublishedID := f.addTask(t, 20, "")
+
+	err := f.service.PublishTask(context.Background(), &models.PublishTaskRequest{User: f.customer, TaskID: taskID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	application, err := f.service.Apply(context.Background(), &models.ApplyRequest{User: loader, TaskID: taskID, AskingPrice: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := testutil.ToFloat64(metrics.TasksStarted)
+	spent := testutil.ToFloat64(metrics.CapitalSpent)
+	unpublished := testutil.ToFloat64(metrics.TasksFailed.WithLabelValues("unpublished"))
+
+	err = f.service.ConfirmCrew(context.Background(), &models.ConfirmCrewRequest{User: f.customer, TaskID: unpublishedID, ApplicationIDs: []uuid.UUID{application.ApplicationID}})
+	if err == nil {
+		t.Fatal("expected error for unpublished task")
+	}
+	err = f.service.ConfirmCrew(context.Background(), &models.ConfirmCrewRequest{User: f.customer, TaskID: taskID, ApplicationIDs: []uuid.UUID{application.ApplicationID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if capital := f.capital(t); capital != 4300 {
+		t.Errorf("capital = %d, want 4300", capital)
+	}
+	if got := testutil.ToFloat64(metrics.TasksStarted) - started; got != 1 {
+		t.Errorf("tasks started = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.CapitalSpent) - spent; got != 700 {
+		t.Errorf("capital spent = %v, want 700", got)
+	}
+	if got := testutil.ToFloat64(metrics.TasksFailed.WithLabelValues("unpublished")) - unpublished; got != 1 {
+		t.Errorf("failed for unpublished task = %v, want 1", got)
+	}
+}
+
+func TestConfirmCrewPaysContractRate(t *testing.T) {
+	f := newFixture(t, 5000, rulesService.RulesetDefault)
+	contractedID := f.addLoader(t, models.Loader{MaxWeight: 30, Salary: 1000}, 500)
+	freeID := f.addFreeLoader(t, models.Loader{MaxWeight: 30, Salary: 1000})
+	taskID := f.addTask(t, 50, "")
+
+	err := f.service.PublishTask(context.Background(), &models.PublishTaskRequest{User: f.customer, TaskID: taskID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applicationIDs []uuid.UUID
+	for _, loaderID := range []uuid.UUID{contractedID, freeID} {
+		application, err := f.service.Apply(context.Background(), &models.ApplyRequest{User: &models.User{UserID: loaderID, UserType: "loader"}, TaskID: taskID, AskingPrice: 1200})
+		if err != nil {
+			t.Fatal(err)
+		}
+		applicationIDs = append(applicationIDs, application.ApplicationID)
+	}
+
+	err = f.service.ConfirmCrew(context.Background(), &models.ConfirmCrewRequest{User: f.customer, TaskID: taskID, ApplicationIDs: applicationIDs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Грузчик по договору получает 500 вместо цены отклика, свободный - цену отклика
+	if capital := f.capital(t); capital != 3300 {
+		t.Errorf("capital = %d, want 3300", capital)
+	}
+	prices, err := f.repos.Tasks.GetTaskLoaderPrices(context.Background(), taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices[contractedID] != 500 || prices[freeID] != 1200 {
+		t.Errorf("prices = %v, want %d for the contracted loader and %d for the free one", prices, 500, 1200)
+	}
+}
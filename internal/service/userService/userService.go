@@ -3,60 +3,99 @@ package userService
 import (
 	"context"
 	"errors"
-	"fmt"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/metrics"
 	"github.com/AhegaoHD/WBT/internal/models"
-	"github.com/AhegaoHD/WBT/pkg/postgres"
+	"github.com/AhegaoHD/WBT/pkg/clock"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/AhegaoHD/WBT/pkg/random"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/bcrypt"
-	"math/rand"
-	"time"
 )
 
 type UserService struct {
-	db                 *postgres.Postgres
+	tx                 txManager
 	userRepository     userRepository
 	customerRepository customerRepository
 	loaderRepository   loaderRepository
 	taskRepository     taskRepository
+	gameRepository     gameRepository
+	cargoTypes         []config.CargoType
+	progression        config.Progression
+	game               config.Game
+	rulesets           rulesets
+	seed               int64 // seed игры: из него и номера регистрации выводятся значения нового пользователя
+	clock              clock.Clock
+	passwordCost       int // стоимость bcrypt; симулятору с тысячами регистраций нужна bcrypt.MinCost
+}
+
+type txManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type gameRepository interface {
+	NextRegistration(ctx context.Context) (int64, error)
+}
+
+type rulesets interface {
+	Name(name string) (string, error)
 }
 
 type userRepository interface {
-	CreateUser(ctx context.Context, user *models.User, tx pgx.Tx) (*models.User, error)
+	CreateUser(ctx context.Context, user *models.User) (*models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
-	UsernameExists(ctx context.Context, username string, tx pgx.Tx) (bool, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
 }
 
 type customerRepository interface {
-	CreateCustomer(ctx context.Context, customer *models.Customer, tx pgx.Tx) error
-	HasCustomers(ctx context.Context, tx pgx.Tx) (bool, error)
+	CreateCustomer(ctx context.Context, customer *models.Customer) error
+	HasCustomers(ctx context.Context) (bool, error)
 	GetCustomerByID(ctx context.Context, customerID uuid.UUID) (*models.Customer, error)
 }
 
 type loaderRepository interface {
-	CreateLoader(ctx context.Context, loader *models.Loader, tx pgx.Tx) error
+	CreateLoader(ctx context.Context, loader *models.Loader) error
 	GetLoaderByID(ctx context.Context, loaderID uuid.UUID) (*models.Loader, error)
 	GetLoaders(ctx context.Context) ([]models.Loader, error)
+	FindLoaders(ctx context.Context, filter *models.LoaderFilter) ([]models.Loader, error)
 }
 
 type taskRepository interface {
-	CreateTasks(ctx context.Context, tasks []models.Task, tx pgx.Tx) error
+	CreateTasks(ctx context.Context, tasks []models.Task) error
 	GetTasksCustomers(ctx context.Context, customerID uuid.UUID) ([]models.Task, error)
 	GetTasksLoaders(ctx context.Context, loaderID uuid.UUID) ([]models.Task, error)
 }
 
-func NewUserService(db *postgres.Postgres, userRepository userRepository, customerRepository customerRepository, loaderRepository loaderRepository, taskRepository taskRepository) *UserService {
-	return &UserService{db: db, userRepository: userRepository, customerRepository: customerRepository, loaderRepository: loaderRepository, taskRepository: taskRepository}
+func NewUserService(tx txManager, userRepository userRepository, customerRepository customerRepository, loaderRepository loaderRepository, taskRepository taskRepository, gameRepository gameRepository, cargoTypes []config.CargoType, progression config.Progression, game config.Game, rulesets rulesets, seed int64, clock clock.Clock, passwordCost int) *UserService {
+	return &UserService{tx: tx, userRepository: userRepository, customerRepository: customerRepository, loaderRepository: loaderRepository, taskRepository: taskRepository, gameRepository: gameRepository, cargoTypes: cargoTypes, progression: progression, game: game, rulesets: rulesets, seed: seed, clock: clock, passwordCost: passwordCost}
 }
 
 func (s *UserService) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
-	tx, err := s.db.Pool.Begin(ctx)
+	// Хеширование пароля до транзакции: при повторе транзакции пароль не хешируется дважды
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), s.passwordCost)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = string(hashedPassword)
+
+	var created *models.User
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		newUser := *user
+		var err error
+		created, err = s.createUser(ctx, &newUser)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, err
 	}
-	defer tx.Rollback(ctx)
+	logger.FromContext(ctx).With("new_user_id", created.UserID, "user_type", created.UserType).Info("user registered")
+	metrics.UsersRegistered.WithLabelValues(created.UserType).Inc()
+	return created, nil
+}
 
-	exist, err := s.userRepository.UsernameExists(ctx, user.Username, tx)
+// createUser регистрирует пользователя с уже захешированным паролем внутри транзакции из ctx.
+func (s *UserService) createUser(ctx context.Context, user *models.User) (*models.User, error) {
+	exist, err := s.userRepository.UsernameExists(ctx, user.Username)
 	if err != nil {
 		return nil, err
 	}
@@ -64,76 +103,84 @@ func (s *UserService) CreateUser(ctx context.Context, user *models.User) (*model
 		return nil, errors.New("username exist")
 	}
 
-	// Хеширование пароля
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	user, err = s.userRepository.CreateUser(ctx, user)
 	if err != nil {
 		return nil, err
 	}
-	user.Password = string(hashedPassword)
 
-	user, err = s.userRepository.CreateUser(ctx, user, tx)
+	// Каждая регистрация получает свой поток случайных чисел: n-я регистрация игры
+	// получает те же значения и после перезапуска, и при повторе транзакции
+	n, err := s.gameRepository.NextRegistration(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	rand.Seed(time.Now().UnixNano())
+	rnd := random.New(random.Derive(s.seed, n))
 
 	switch user.UserType {
 	case "customer":
-		exist, err := s.customerRepository.HasCustomers(ctx, tx)
+		exist, err := s.customerRepository.HasCustomers(ctx)
 		if err != nil {
 			return nil, err
 		}
 		if exist == true {
 			return nil, errors.New("exist")
 		}
+		ruleset, err := s.rulesets.Name(user.Ruleset)
+		if err != nil {
+			return nil, err
+		}
 
 		customer := &models.Customer{
 			CustomerID: user.UserID,
-			Capital:    rand.Intn(100000-10000+1) + 10000,
+			Capital:    s.game.Capital.Pick(rnd.Intn),
+			Ruleset:    ruleset,
 		}
-		err = s.customerRepository.CreateCustomer(ctx, customer, tx)
+		err = s.customerRepository.CreateCustomer(ctx, customer)
 		if err != nil {
 			return nil, err
 		}
 
-		taskCount := rand.Intn(5) + 1
+		taskCount := s.game.TaskCount.Pick(rnd.Intn)
 		tasks := make([]models.Task, 0, taskCount)
 		for i := 0; i < taskCount; i++ {
+			weight := s.game.TaskWeight.Pick(rnd.Intn)
+			// Последний вариант - общий груз без ограничений
+			var cargoType string
+			if pick := rnd.Intn(len(s.cargoTypes) + 1); pick < len(s.cargoTypes) {
+				cargoType = s.cargoTypes[pick].Name
+			}
 			tasks = append(tasks, models.Task{
-				CustomerID:  user.UserID,
-				Weight:      rand.Intn(80-10+1) + 10,
-				Description: "",
-				Status:      false,
+				CustomerID:      user.UserID,
+				Weight:          weight,
+				RemainingWeight: weight,
+				CargoType:       cargoType,
+				Description:     "",
+				Status:          false,
 			})
 		}
-		err = s.taskRepository.CreateTasks(ctx, tasks, tx)
+		err = s.taskRepository.CreateTasks(ctx, tasks)
 		if err != nil {
 			return nil, err
 		}
 
 	case "loader":
 		var drunk bool
-		if rand.Intn(2) == 0 {
+		if rnd.Intn(100) < s.game.DrunkPercent {
 			drunk = true
 		}
 		loader := &models.Loader{
 			LoaderID:  user.UserID,
-			MaxWeight: rand.Intn(30-5+1) + 5,
+			MaxWeight: s.game.LoaderMaxWeight.Pick(rnd.Intn),
 			Drunk:     drunk,
-			Fatigue:   rand.Intn(101),
-			Salary:    rand.Intn(30000-10000+1) + 10000,
+			Fatigue:   s.game.LoaderFatigue.Pick(rnd.Intn),
+			Salary:    s.game.LoaderSalary.Pick(rnd.Intn),
 		}
-		err = s.loaderRepository.CreateLoader(ctx, loader, tx)
+		err = s.loaderRepository.CreateLoader(ctx, loader)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	err = tx.Commit(ctx) // Завершаем транзакцию после всех операций
-	if err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
 	return user, nil
 }
 
@@ -155,10 +202,7 @@ func (s *UserService) AuthenticateUser(ctx context.Context, credentials *models.
 func (s *UserService) GetUserDetails(ctx context.Context, user *models.User) (interface{}, error) {
 	switch user.UserType {
 	case "customer":
-		var customerResponce struct {
-			Info    *models.Customer `json:"info"`
-			Loaders []models.Loader  `json:"loaders"`
-		}
+		customerResponce := &models.CustomerDetails{}
 		info, err := s.customerRepository.GetCustomerByID(ctx, user.UserID)
 		if err != nil {
 			return nil, err
@@ -170,9 +214,20 @@ func (s *UserService) GetUserDetails(ctx context.Context, user *models.User) (in
 			return nil, err
 		}
 		customerResponce.Loaders = loaders
+		customerResponce.GameTime = s.clock.Now()
 		return customerResponce, nil
 	case "loader":
-		return s.loaderRepository.GetLoaderByID(ctx, user.UserID)
+		loaderResponce := &models.LoaderDetails{}
+		loader, err := s.loaderRepository.GetLoaderByID(ctx, user.UserID)
+		if err != nil {
+			return nil, err
+		}
+		loaderResponce.Loader = loader
+		loaderResponce.GameTime = s.clock.Now()
+		if loader.Level < len(s.progression.Levels) {
+			loaderResponce.NextLevelExperience = &s.progression.Levels[loader.Level].Experience
+		}
+		return loaderResponce, nil
 	default:
 		return nil, errors.New("err")
 	}
@@ -188,3 +243,19 @@ func (s *UserService) GetUserTasks(ctx context.Context, user *models.User) (inte
 		return nil, errors.New("err")
 	}
 }
+
+// GetLoader возвращает заказчику грузчика по идентификатору.
+func (s *UserService) GetLoader(ctx context.Context, user *models.User, loaderID uuid.UUID) (*models.Loader, error) {
+	if user.UserType != "customer" {
+		return nil, errors.New("not customer")
+	}
+	return s.loaderRepository.GetLoaderByID(ctx, loaderID)
+}
+
+// FindLoaders возвращает заказчику список грузчиков с рейтингами, отфильтрованный и отсортированный по filter.
+func (s *UserService) FindLoaders(ctx context.Context, user *models.User, filter *models.LoaderFilter) ([]models.Loader, error) {
+	if user.UserType != "customer" {
+		return nil, errors.New("not customer")
+	}
+	return s.loaderRepository.FindLoaders(ctx, filter)
+}
@@ -0,0 +1,236 @@
+package userService_test
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/internal/repository"
+	"github.com/AhegaoHD/WBT/internal/repository/memoryRepository"
+	"github.com/AhegaoHD/WBT/internal/service/rulesService"
+	"github.com/AhegaoHD/WBT/internal/service/userService"
+	"github.com/AhegaoHD/WBT/pkg/clock"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+)
+
+func newUserService(t *testing.T, game config.Game) (*userService.UserService, *repository.Repositories) {
+	t.Helper()
+	repos := memoryRepository.NewRepositories(memoryRepository.NewStore())
+	_, err := repos.Games.GetOrCreateSeed(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return startUserService(t, game, repos), repos
+}
+
+// startUserService создает UserService над уже созданной игрой в repos, как при запуске приложения.
+func startUserService(t *testing.T, game config.Game, repos *repository.Repositories) *userService.UserService {
+	t.Helper()
+	cargoTypes := []config.CargoType{{Name: "fragile", ForbidDrunk: true}}
+	rules, err := rulesService.NewRegistry(game, cargoTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := userService.NewUserService(repos.Tx, repos.Users, repos.Customers, repos.Loaders, repos.Tasks, repos.Games, cargoTypes, config.Progression{}, game, rules, 1,
+		clock.NewFake(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)), bcrypt.MinCost)
+	return service
+}
+
+func TestCreateCustomerUsesGameRanges(t *testing.T) {
+	game := config.DefaultGame()
+	game.Capital = config.Range{Min: 500, Max: 600}
+	game.TaskCount = config.Range{Min: 3, Max: 3}
+	game.TaskWeight = config.Range{Min: 10, Max: 12}
+	service, repos := newUserService(t, game)
+	ctx := context.Background()
+
+	user, err := service.CreateUser(ctx, &models.User{Username: "customer", Password: "secret", UserType: "customer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customer, err := repos.Customers.GetCustomerByID(ctx, user.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customer.Capital < 500 || customer.Capital > 600 {
+		t.Errorf("capital = %d, want 500..600", customer.Capital)
+	}
+	if customer.Ruleset != rulesService.RulesetDefault {
+		t.Errorf("ruleset = %q, want %q", customer.Ruleset, rulesService.RulesetDefault)
+	}
+
+	tasks, err := repos.Tasks.GetTasksCustomers(ctx, user.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("%d tasks, want 3", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.Weight < 10 || task.Weight > 12 || task.RemainingWeight != task.Weight {
+			t.Errorf("task = %+v, want weight 10..12", task)
+		}
+		if task.CargoType != "" && task.CargoType != "fragile" {
+			t.Errorf("cargo type = %q", task.CargoType)
+		}
+	}
+}
+
+func TestCreateLoaderUsesGameRanges(t *testing.T) {
+	game := config.DefaultGame()
+	game.LoaderMaxWeight = config.Range{Min: 7, Max: 7}
+	game.LoaderSalary = config.Range{Min: 100, Max: 200}
+	game.LoaderFatigue = config.Range{Min: 0, Max: 10}
+	game.DrunkPercent = 100
+	service, repos := newUserService(t, game)
+	ctx := context.Background()
+
+	user, err := service.CreateUser(ctx, &models.User{Username: "loader", Password: "secret", UserType: "loader"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader, err := repos.Loaders.GetLoaderByID(ctx, user.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loader.MaxWeight != 7 || loader.Salary < 100 || loader.Salary > 200 || loader.Fatigue > 10 || !loader.Drunk {
+		t.Errorf("loader = %+v", loader)
+	}
+}
+
+func TestCreateUserRejectsDuplicates(t *testing.T) {
+	service, _ := newUserService(t, config.DefaultGame())
+	ctx := context.Background()
+
+	_, err := service.CreateUser(ctx, &models.User{Username: "loader", Password: "secret", UserType: "loader"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.CreateUser(ctx, &models.User{Username: "loader", Password: "secret", UserType: "loader"})
+	if err == nil {
+		t.Error("expected error for duplicate username")
+	}
+}
+
+func TestCreateUserAllowsSingleCustomer(t *testing.T) {
+	service, repos := newUserService(t, config.DefaultGame())
+	ctx := context.Background()
+
+	_, err := service.CreateUser(ctx, &models.User{Username: "first", Password: "secret", UserType: "customer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.CreateUser(ctx, &models.User{Username: "second", Password: "secret", UserType: "customer"})
+	if err == nil {
+		t.Fatal("expected error for second customer")
+	}
+
+	// Пользователь второго заказчика откатывается вместе с транзакцией
+	_, err = repos.Users.GetUserByUsername(ctx, "second")
+	if err == nil {
+		t.Error("second customer user was not rolled back")
+	}
+}
+
+func TestCreateUserRuleset(t *testing.T) {
+	service, repos := newUserService(t, config.DefaultGame())
+	ctx := context.Background()
+
+	_, err := service.CreateUser(ctx, &models.User{Username: "bad", Password: "secret", UserType: "customer", Ruleset: "unknown"})
+	if err == nil {
+		t.Fatal("expected error for unknown ruleset")
+	}
+
+	user, err := service.CreateUser(ctx, &models.User{Username: "customer", Password: "secret", UserType: "customer", Ruleset: rulesService.RulesetPiecework})
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer, err := repos.Customers.GetCustomerByID(ctx, user.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customer.Ruleset != rulesService.RulesetPiecework {
+		t.Errorf("ruleset = %q, want %q", customer.Ruleset, rulesService.RulesetPiecework)
+	}
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	service, _ := newUserService(t, config.DefaultGame())
+	ctx := context.Background()
+
+	_, err := service.CreateUser(ctx, &models.User{Username: "loader", Password: "secret", UserType: "loader"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.AuthenticateUser(ctx, &models.User{Username: "loader", Password: "secret"})
+	if err != nil {
+		t.Errorf("valid credentials: %v", err)
+	}
+	_, err = service.AuthenticateUser(ctx, &models.User{Username: "loader", Password: "wrong"})
+	if err == nil {
+		t.Error("expected error for wrong password")
+	}
+}
+
+func TestCreateUserIsDeterministic(t *testing.T) {
+	capital := func() int {
+		service, repos := newUserService(t, config.DefaultGame())
+		user, err := service.CreateUser(context.Background(), &models.User{Username: "customer", Password: "secret", UserType: "customer"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		customer, err := repos.Customers.GetCustomerByID(context.Background(), user.UserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return customer.Capital
+	}
+
+	if first, second := capital(), capital(); first != second {
+		t.Errorf("capital %d != %d with the same seed", first, second)
+	}
+}
+
+func TestCreateUserContinuesAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	register := func(service *userService.UserService, repos *repository.Repositories, username string) models.Loader {
+		t.Helper()
+		user, err := service.CreateUser(ctx, &models.User{Username: username, Password: "secret", UserType: "loader"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		loader, err := repos.Loaders.GetLoaderByID(ctx, user.UserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *loader
+	}
+	game := config.DefaultGame()
+
+	service, repos := newUserService(t, game)
+	register(service, repos, "first")
+	want := register(service, repos, "second")
+
+	// Тот же путь с перезапуском сервиса между регистрациями: вторая регистрация
+	// продолжает игру, а не повторяет первую
+	service, repos = newUserService(t, game)
+	first := register(service, repos, "first")
+	got := register(startUserService(t, game, repos), repos, "second")
+
+	if !sameLoader(got, want) {
+		t.Errorf("after restart loader = %+v, want %+v", got, want)
+	}
+	if sameLoader(first, got) {
+		t.Errorf("second registration repeated the first: %+v", got)
+	}
+}
+
+// sameLoader сравнивает сгенерированные при регистрации значения грузчиков.
+func sameLoader(a, b models.Loader) bool {
+	return a.MaxWeight == b.MaxWeight && a.Salary == b.Salary && a.Fatigue == b.Fatigue && a.Drunk == b.Drunk
+}
@@ -0,0 +1,141 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/internal/repository/memoryRepository"
+	"github.com/AhegaoHD/WBT/internal/service/contractService"
+	"github.com/AhegaoHD/WBT/internal/service/pricingService"
+	"github.com/AhegaoHD/WBT/internal/service/rulesService"
+	"github.com/AhegaoHD/WBT/internal/service/taskService"
+	"github.com/AhegaoHD/WBT/internal/service/userService"
+	"github.com/AhegaoHD/WBT/pkg/clock"
+	"github.com/AhegaoHD/WBT/pkg/random"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+// maxTurns ограничивает длину партии, если стратегия продолжает запускать задачи бесконечно.
+const maxTurns = 1000
+
+// startTime - игровое время симуляции. Часы стоят, чтобы ночной тариф не зависел от момента запуска.
+var startTime = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// Game - одна партия: заказчик и грузчики, нанятые им по рыночной цене, в отдельном хранилище в памяти.
+type Game struct {
+	Customer *models.User
+	// Loaders - штат заказчика.
+	Loaders []uuid.UUID
+	Tasks   *taskService.TaskService
+	// Random - источник случайности для стратегий, не зависящий от событий игры.
+	Random *random.Rand
+
+	customers *memoryRepository.CustomerRepository
+	capital   int
+	total     int
+}
+
+// Result - итог партии.
+type Result struct {
+	Won         bool
+	Capital     int
+	CapitalLeft int
+	TasksTotal  int
+	TasksDone   int
+}
+
+// NewGame регистрирует заказчика и loaders грузчиков через настоящие сервисы
+// и нанимает всех грузчиков по договорам с рыночной ценой.
+func NewGame(ctx context.Context, cfg *config.Config, rules *rulesService.Registry, pricer pricingService.Pricer, loaders int, seed int64) (*Game, error) {
+	store := memoryRepository.NewStore()
+	userRepository := memoryRepository.NewUserRepository(store)
+	customerRepository := memoryRepository.NewCustomerRepository(store)
+	loaderRepository := memoryRepository.NewLoaderRepository(store)
+	taskRepository := memoryRepository.NewTaskRepository(store)
+	contractRepository := memoryRepository.NewContractRepository(store)
+	gameRepository := memoryRepository.NewGameRepository(store)
+	_, err := gameRepository.GetOrCreateSeed(ctx, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	gameClock := clock.NewFake(startTime)
+	users := userService.NewUserService(store, userRepository, customerRepository, loaderRepository, taskRepository, gameRepository, cfg.CargoTypes, cfg.Progression, cfg.Game, rules, seed, gameClock, bcrypt.MinCost)
+	tasks := taskService.NewTaskService(store, taskRepository, loaderRepository, customerRepository, contractRepository, nil, rules, cfg.Damage, cfg.Progression, seed+1, pricer, gameClock)
+	contracts := contractService.NewContractService(store, contractRepository, customerRepository)
+
+	customer, err := users.CreateUser(ctx, &models.User{Username: "customer", Password: "customer", UserType: "customer"})
+	if err != nil {
+		return nil, err
+	}
+	loaderIDs := make([]uuid.UUID, 0, loaders)
+	for i := 0; i < loaders; i++ {
+		username := fmt.Sprintf("loader%d", i)
+		loader, err := users.CreateUser(ctx, &models.User{Username: username, Password: username, UserType: "loader"})
+		if err != nil {
+			return nil, err
+		}
+		contract, err := contracts.OfferContract(ctx, &models.OfferContractRequest{User: customer, LoaderID: loader.UserID, Kind: models.ContractPerTask})
+		if err != nil {
+			return nil, err
+		}
+		err = contracts.AcceptContract(ctx, loader, contract.ContractID)
+		if err != nil {
+			return nil, err
+		}
+		loaderIDs = append(loaderIDs, loader.UserID)
+	}
+
+	info, err := customerRepository.GetCustomerByID(ctx, customer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	queue, err := tasks.GetTaskQueue(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Game{
+		Customer:  customer,
+		Loaders:   loaderIDs,
+		Tasks:     tasks,
+		Random:    random.New(seed + 2),
+		customers: customerRepository,
+		capital:   info.Capital,
+		total:     len(queue),
+	}, nil
+}
+
+// Play ходит стратегией, пока она запускает задачи, и возвращает итог партии.
+// Партия выиграна, если выполнены все задачи.
+func (g *Game) Play(ctx context.Context, strategy Strategy) (*Result, error) {
+	for turn := 0; turn < maxTurns; turn++ {
+		progress, err := strategy.Turn(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		if !progress {
+			break
+		}
+	}
+
+	queue, err := g.Tasks.GetTaskQueue(ctx, g.Customer)
+	if err != nil {
+		return nil, err
+	}
+	info, err := g.customers.GetCustomerByID(ctx, g.Customer.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Won:         len(queue) == 0,
+		Capital:     g.capital,
+		CapitalLeft: info.Capital,
+		TasksTotal:  g.total,
+		TasksDone:   g.total - len(queue),
+	}, nil
+}
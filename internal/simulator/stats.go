@@ -0,0 +1,81 @@
+package simulator
+
+// completionBuckets - границы корзин распределения доли выполненных задач, в процентах.
+var completionBuckets = []struct {
+	Label    string
+	From, To int
+}{
+	{"0%", 0, 0},
+	{"1-24%", 1, 24},
+	{"25-49%", 25, 49},
+	{"50-74%", 50, 74},
+	{"75-99%", 75, 99},
+	{"100%", 100, 100},
+}
+
+// Stats - сводка по партиям одной стратегии.
+type Stats struct {
+	Games       int
+	Wins        int
+	capitalLeft int64
+	capital     int64
+	// Completion - число партий по корзинам доли выполненных задач, в порядке CompletionLabels.
+	Completion []int
+}
+
+func NewStats() *Stats {
+	return &Stats{Completion: make([]int, len(completionBuckets))}
+}
+
+func (s *Stats) Add(result *Result) {
+	s.Games++
+	if result.Won {
+		s.Wins++
+	}
+	s.capital += int64(result.Capital)
+	s.capitalLeft += int64(result.CapitalLeft)
+
+	percent := 100
+	if result.TasksTotal > 0 {
+		percent = result.TasksDone * 100 / result.TasksTotal
+	}
+	if percent == 0 && result.TasksDone > 0 {
+		percent = 1
+	}
+	for i, bucket := range completionBuckets {
+		if percent >= bucket.From && percent <= bucket.To {
+			s.Completion[i]++
+			break
+		}
+	}
+}
+
+func (s *Stats) WinRate() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+	return float64(s.Wins) / float64(s.Games)
+}
+
+func (s *Stats) AvgCapital() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+	return float64(s.capital) / float64(s.Games)
+}
+
+func (s *Stats) AvgCapitalLeft() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+	return float64(s.capitalLeft) / float64(s.Games)
+}
+
+// CompletionLabels возвращает подписи корзин Stats.Completion.
+func CompletionLabels() []string {
+	labels := make([]string, 0, len(completionBuckets))
+	for _, bucket := range completionBuckets {
+		labels = append(labels, bucket.Label)
+	}
+	return labels
+}
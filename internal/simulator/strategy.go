@@ -0,0 +1,107 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"github.com/AhegaoHD/WBT/internal/models"
+	"github.com/AhegaoHD/WBT/internal/service/taskService"
+	"github.com/google/uuid"
+)
+
+const (
+	StrategyGreedy   = "greedy"
+	StrategyCheapest = "cheapest"
+	StrategyRandom   = "random"
+)
+
+// randomAttempts - сколько случайных бригад пробует StrategyRandom за ход.
+const randomAttempts = 20
+
+// Strategy - как заказчик распоряжается своим штатом.
+type Strategy interface {
+	// Turn делает один ход и сообщает, была ли запущена хотя бы одна задача.
+	Turn(ctx context.Context, g *Game) (bool, error)
+}
+
+func NewStrategy(name string) (Strategy, error) {
+	switch name {
+	case StrategyGreedy:
+		return Greedy{}, nil
+	case StrategyCheapest:
+		return Cheapest{}, nil
+	case StrategyRandom:
+		return Random{}, nil
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", name)
+	}
+}
+
+// Greedy идет по очереди задач и набирает на каждую самых сильных грузчиков (AutoDispatch).
+// Задачи, которые нельзя закончить за рейс, штат начинает частичными рейсами.
+type Greedy struct{}
+
+func (Greedy) Turn(ctx context.Context, g *Game) (bool, error) {
+	result, err := g.Tasks.AutoDispatch(ctx, g.Customer)
+	if err != nil {
+		return false, err
+	}
+	return len(result.Started) > 0, nil
+}
+
+// Cheapest выполняет план с самыми дешевыми бригадами (PlanTasks). Задачи, которые
+// нельзя закончить за один рейс, план начинает частичными рейсами.
+type Cheapest struct{}
+
+func (Cheapest) Turn(ctx context.Context, g *Game) (bool, error) {
+	plan, err := g.Tasks.PlanTasks(ctx, g.Customer)
+	if err != nil {
+		return false, err
+	}
+	if len(plan.Assignments) > 0 {
+		// План может не выполниться, если штрафы за порчу съели капитал, - тогда ходит Greedy
+		_, err = g.Tasks.ExecutePlan(ctx, g.Customer, plan)
+		if err == nil {
+			return true, nil
+		}
+		if !taskService.Rejected(err) {
+			return false, err
+		}
+	}
+	return Greedy{}.Turn(ctx, g)
+}
+
+// Random отправляет на случайную задачу случайную бригаду из штата. Бригада, которую
+// игра отклонила, - не ход; остальные ошибки прерывают игру.
+type Random struct{}
+
+func (Random) Turn(ctx context.Context, g *Game) (bool, error) {
+	queue, err := g.Tasks.GetTaskQueue(ctx, g.Customer)
+	if err != nil {
+		return false, err
+	}
+	if len(queue) == 0 || len(g.Loaders) == 0 {
+		return false, nil
+	}
+
+	for attempt := 0; attempt < randomAttempts; attempt++ {
+		task := queue[g.Random.Intn(len(queue))]
+		var crew []uuid.UUID
+		for _, loaderID := range g.Loaders {
+			if g.Random.Intn(2) == 0 {
+				crew = append(crew, loaderID)
+			}
+		}
+		if len(crew) == 0 {
+			crew = append(crew, g.Loaders[g.Random.Intn(len(g.Loaders))])
+		}
+
+		err = g.Tasks.StartTask(ctx, &models.StartTaskRequest{User: g.Customer, TaskID: task.TaskID, LoaderIDs: crew})
+		if err == nil {
+			return true, nil
+		}
+		if !taskService.Rejected(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,5 @@
+ALTER TABLE tasks
+    ADD COLUMN priority INT NOT NULL DEFAULT 0,
+    ADD COLUMN deadline TIMESTAMPTZ;
+
+CREATE INDEX tasks_queue_idx ON tasks (customer_id, priority DESC, deadline ASC NULLS LAST) WHERE status = false;
@@ -0,0 +1,5 @@
+ALTER TABLE tasks ADD COLUMN remaining_weight INT;
+UPDATE tasks SET remaining_weight = CASE WHEN status THEN 0 ELSE weight END;
+ALTER TABLE tasks ALTER COLUMN remaining_weight SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS task_loaders_task_loader_idx ON task_loaders (task_id, loader_id);
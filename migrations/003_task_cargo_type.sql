@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN cargo_type TEXT NOT NULL DEFAULT '';
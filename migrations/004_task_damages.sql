@@ -0,0 +1,13 @@
+ALTER TABLE tasks ADD COLUMN damage_penalty INT NOT NULL DEFAULT 0;
+
+CREATE TABLE task_damages
+(
+    damage_id  UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    task_id    UUID        NOT NULL REFERENCES tasks (task_id),
+    loader_id  UUID        NOT NULL REFERENCES loaders (loader_id),
+    penalty    INT         NOT NULL,
+    charged_to TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX task_damages_task_idx ON task_damages (task_id);
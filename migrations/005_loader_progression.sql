@@ -0,0 +1,3 @@
+ALTER TABLE loaders
+    ADD COLUMN experience INT NOT NULL DEFAULT 0,
+    ADD COLUMN level      INT NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+CREATE TABLE contracts
+(
+    contract_id UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    customer_id UUID        NOT NULL REFERENCES customers (customer_id),
+    loader_id   UUID        NOT NULL REFERENCES loaders (loader_id),
+    kind        TEXT        NOT NULL CHECK (kind IN ('per_task', 'retainer')),
+    rate        INT         NOT NULL CHECK (rate > 0),
+    status      TEXT        NOT NULL CHECK (status IN ('offered', 'active', 'declined', 'terminated')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Грузчик работает не больше чем по одному действующему договору,
+-- и у заказчика не больше одного открытого предложения одному грузчику
+CREATE UNIQUE INDEX contracts_active_loader_idx ON contracts (loader_id) WHERE status = 'active';
+CREATE UNIQUE INDEX contracts_open_offer_idx ON contracts (customer_id, loader_id) WHERE status IN ('offered', 'active');
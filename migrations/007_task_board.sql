@@ -0,0 +1,14 @@
+ALTER TABLE tasks ADD COLUMN published BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE applications
+(
+    application_id UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    task_id        UUID        NOT NULL REFERENCES tasks (task_id),
+    loader_id      UUID        NOT NULL REFERENCES loaders (loader_id),
+    asking_price   INT         NOT NULL CHECK (asking_price > 0),
+    status         TEXT        NOT NULL CHECK (status IN ('pending', 'accepted', 'rejected')),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Один ожидающий отклик грузчика на задачу
+CREATE UNIQUE INDEX applications_pending_idx ON applications (task_id, loader_id) WHERE status = 'pending';
@@ -0,0 +1,5 @@
+ALTER TABLE task_loaders ADD COLUMN price INT NOT NULL DEFAULT 0;
+
+-- Рыночная цена договора по рейсам задается нулевой ставкой
+ALTER TABLE contracts DROP CONSTRAINT contracts_rate_check;
+ALTER TABLE contracts ADD CONSTRAINT contracts_rate_check CHECK (rate > 0 OR (kind = 'per_task' AND rate = 0));
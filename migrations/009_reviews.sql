@@ -0,0 +1,14 @@
+CREATE TABLE reviews
+(
+    review_id  UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    task_id    UUID        NOT NULL REFERENCES tasks (task_id),
+    author_id  UUID        NOT NULL REFERENCES users (user_id),
+    target_id  UUID        NOT NULL REFERENCES users (user_id),
+    rating     INT         NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment    TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- Один отзыв участника о другом участнике по задаче
+    UNIQUE (task_id, author_id, target_id)
+);
+
+CREATE INDEX reviews_target_idx ON reviews (target_id);
@@ -0,0 +1,2 @@
+-- Набор правил игры выбирается заказчиком при регистрации
+ALTER TABLE customers ADD COLUMN ruleset TEXT NOT NULL DEFAULT 'default';
@@ -0,0 +1,7 @@
+-- В базе одна игра; seed фиксируется при первом запуске, чтобы игру можно было воспроизвести
+CREATE TABLE games
+(
+    game_id    INT PRIMARY KEY      DEFAULT 1 CHECK (game_id = 1),
+    seed       BIGINT      NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,9 @@
+-- Версия строки растет при каждом обновлении; обновление с устаревшей версией отклоняется
+ALTER TABLE customers
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE loaders
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE tasks
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+-- Счетчик регистраций: значения n-й регистрации выводятся из seed игры и n,
+-- поэтому после перезапуска генератор не начинает игру заново
+ALTER TABLE games
+    ADD COLUMN registrations BIGINT NOT NULL DEFAULT 0;
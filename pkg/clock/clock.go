@@ -0,0 +1,71 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock - источник текущего времени.
+type Clock interface {
+	Now() time.Time
+}
+
+// New возвращает часы игры, созданной в epoch. При factor > 1 игровое время с epoch идет
+// в factor раз быстрее реального, поэтому после перезапуска оно продолжается с того же места,
+// а простой приложения тоже ускоряется. При factor 0 или 1 - реальное время.
+func New(epoch time.Time, factor float64) Clock {
+	if factor > 1 {
+		return NewAccelerated(epoch, factor)
+	}
+	return Real{}
+}
+
+// Real - системные часы.
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Accelerated - часы, по которым с epoch прошло в factor раз больше времени, чем по реальным.
+type Accelerated struct {
+	epoch  time.Time
+	factor float64
+}
+
+func NewAccelerated(epoch time.Time, factor float64) *Accelerated {
+	return &Accelerated{epoch: epoch, factor: factor}
+}
+
+func (c *Accelerated) Now() time.Time {
+	elapsed := time.Since(c.epoch)
+	return c.epoch.Add(time.Duration(float64(elapsed) * c.factor))
+}
+
+// Fake - часы, которые двигаются только вручную. Используются в тестах.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (c *Fake) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Fake) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func (c *Fake) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
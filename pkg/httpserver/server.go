@@ -49,6 +49,11 @@ func (s *Server) start() {
 	}()
 }
 
+// Addr - адрес, который слушает сервер.
+func (s *Server) Addr() string {
+	return s.server.Addr
+}
+
 func (s *Server) Notify() <-chan error {
 	return s.notify
 }
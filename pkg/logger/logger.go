@@ -1,13 +1,28 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog"
 )
 
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Имена полей, которые добавляются к логгеру запроса.
+const (
+	FieldRequestID = "request_id"
+	FieldUserID    = "user_id"
+)
+
 type Interface interface {
 	Debug(message interface{}, args ...interface{})
 	Info(message string, args ...interface{})
@@ -17,79 +32,132 @@ type Interface interface {
 }
 
 type Logger struct {
-	logger *zerolog.Logger
+	logger zerolog.Logger
 }
 
 var _ Interface = (*Logger)(nil)
 
-// New -. level: error, warn, info, debug.
-func New(level string) *Logger {
-	var l zerolog.Level
+// fallback - логгер для контекста, в который логгер не положили: фоновые задачи, запуск
+// приложения, симулятор и тесты. Пока не вызван SetDefault, записи отбрасываются.
+var fallback atomic.Pointer[Logger]
+
+func init() {
+	fallback.Store(&Logger{logger: zerolog.Nop()})
+}
+
+// SetDefault делает l логгером для контекстов без логгера запроса.
+func SetDefault(l *Logger) {
+	fallback.Store(l)
+}
+
+// New -. level: error, warn, info, debug; format: json или console (читаемый вывод для разработки).
+func New(level, format string) *Logger {
+	return NewWithWriter(os.Stdout, level, format)
+}
+
+// NewWithWriter - как New, но пишет записи в out, например в буфер в тестах.
+func NewWithWriter(out io.Writer, level, format string) *Logger {
+	l, err := ParseLevel(level)
+	if err != nil {
+		l = zerolog.InfoLevel
+	}
+	if format == FormatConsole {
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339}
+	}
 
+	skipFrameCount := 2
+	logger := zerolog.New(out).Level(l).With().Timestamp().CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + skipFrameCount).Logger()
+
+	return &Logger{
+		logger: logger,
+	}
+}
+
+// ParseLevel переводит уровень из конфига в уровень zerolog.
+func ParseLevel(level string) (zerolog.Level, error) {
 	switch strings.ToLower(level) {
 	case "error":
-		l = zerolog.ErrorLevel
+		return zerolog.ErrorLevel, nil
 	case "warn":
-		l = zerolog.WarnLevel
-	case "info":
-		l = zerolog.InfoLevel
+		return zerolog.WarnLevel, nil
+	case "", "info":
+		return zerolog.InfoLevel, nil
 	case "debug":
-		l = zerolog.DebugLevel
+		return zerolog.DebugLevel, nil
 	default:
-		l = zerolog.InfoLevel
+		return zerolog.InfoLevel, fmt.Errorf("unknown log level %q", level)
 	}
+}
 
-	zerolog.SetGlobalLevel(l)
-
-	skipFrameCount := 3
-	logger := zerolog.New(os.Stdout).With().Timestamp().CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + skipFrameCount).Logger()
-
-	return &Logger{
-		logger: &logger,
-	}
+// With возвращает логгер, добавляющий к каждой записи поля из пар ключ-значение.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	return &Logger{logger: l.logger.With().Fields(keyvals).Logger()}
 }
 
 func (l *Logger) Debug(message interface{}, args ...interface{}) {
-	l.msg("debug", message, args...)
+	l.msg(zerolog.DebugLevel, message, args...)
 }
 
 func (l *Logger) Info(message string, args ...interface{}) {
-	l.log(message, args...)
+	l.msg(zerolog.InfoLevel, message, args...)
 }
 
 func (l *Logger) Warn(message string, args ...interface{}) {
-	l.log(message, args...)
+	l.msg(zerolog.WarnLevel, message, args...)
 }
 
 func (l *Logger) Error(message interface{}, args ...interface{}) {
-	if l.logger.GetLevel() == zerolog.DebugLevel {
-		l.Debug(message, args...)
-	}
-
-	l.msg("error", message, args...)
+	l.msg(zerolog.ErrorLevel, message, args...)
 }
 
 func (l *Logger) Fatal(message interface{}, args ...interface{}) {
-	l.msg("fatal", message, args...)
+	l.msg(zerolog.FatalLevel, message, args...)
 
 	os.Exit(1)
 }
 
-func (l *Logger) log(message string, args ...interface{}) {
-	if len(args) == 0 {
-		l.logger.Info().Msg(message)
-	} else {
-		l.logger.Info().Msgf(message, args...)
+// msg пишет запись уровня level. Ошибка попадает в поле error, а текстом записи становится
+// ее сообщение, чтобы Error(err) и Error("...: %v", err) читались одинаково.
+func (l *Logger) msg(level zerolog.Level, message interface{}, args ...interface{}) {
+	event := l.logger.WithLevel(level)
+	if event == nil {
+		return
 	}
-}
 
-func (l *Logger) msg(level string, message interface{}, args ...interface{}) {
+	var text string
 	switch msg := message.(type) {
 	case error:
-		l.log(msg.Error(), args...)
+		event = event.Err(msg)
+		text = msg.Error()
 	case string:
-		l.log(msg, args...)
+		text = msg
 	default:
-		l.log(fmt.Sprintf("%s message %v has unknown type %v", level, message, msg), args...)
+		text = fmt.Sprintf("%s message %v has unknown type %T", level, message, message)
 	}
+
+	if len(args) == 0 {
+		event.Msg(text)
+	} else {
+		event.Msgf(text, args...)
+	}
+}
+
+type ctxKey struct{}
+
+// WithContext кладет логгер в контекст запроса.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext возвращает логгер запроса, а если логгера в контексте нет - логгер из SetDefault.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return l
+	}
+	return fallback.Load()
+}
+
+// With добавляет поля к логгеру из ctx, например идентификатор пользователя после авторизации.
+func With(ctx context.Context, keyvals ...interface{}) context.Context {
+	return WithContext(ctx, FromContext(ctx).With(keyvals...))
 }
@@ -0,0 +1,125 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"testing"
+)
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		level string
+		want  []string
+	}{
+		{"debug", []string{"debug", "info", "warn", "error"}},
+		{"info", []string{"info", "warn", "error"}},
+		{"", []string{"info", "warn", "error"}},
+		{"WARN", []string{"warn", "error"}},
+		{"error", []string{"error"}},
+		{"verbose", []string{"info", "warn", "error"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := logger.NewWithWriter(&buf, tt.level, logger.FormatJSON)
+			l.Debug("debug")
+			l.Info("info")
+			l.Warn("warn")
+			l.Error("error")
+
+			entries := decode(t, &buf)
+			if len(entries) != len(tt.want) {
+				t.Fatalf("%d entries, want %d", len(entries), len(tt.want))
+			}
+			for i, entry := range entries {
+				if entry["level"] != tt.want[i] || entry["message"] != tt.want[i] {
+					t.Errorf("entry %d = %v, want level and message %q", i, entry, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMessageAndFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     func(l *logger.Logger)
+		message string
+		fields  map[string]interface{}
+	}{
+		{
+			name:    "format args",
+			log:     func(l *logger.Logger) { l.Info("task %d done", 3) },
+			message: "task 3 done",
+		},
+		{
+			name:    "error value",
+			log:     func(l *logger.Logger) { l.Error(errors.New("boom")) },
+			message: "boom",
+			fields:  map[string]interface{}{"error": "boom"},
+		},
+		{
+			name:    "structured fields",
+			log:     func(l *logger.Logger) { l.With(logger.FieldRequestID, "abc", "attempt", 2).Warn("retrying") },
+			message: "retrying",
+			fields:  map[string]interface{}{logger.FieldRequestID: "abc", "attempt": float64(2)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(logger.NewWithWriter(&buf, "debug", logger.FormatJSON))
+
+			entries := decode(t, &buf)
+			if len(entries) != 1 {
+				t.Fatalf("%d entries, want 1", len(entries))
+			}
+			if entries[0]["message"] != tt.message {
+				t.Errorf("message = %v, want %q", entries[0]["message"], tt.message)
+			}
+			for key, want := range tt.fields {
+				if entries[0][key] != want {
+					t.Errorf("%s = %v, want %v", key, entries[0][key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	defer logger.SetDefault(logger.FromContext(context.Background()))
+	var fallback, request bytes.Buffer
+	logger.SetDefault(logger.NewWithWriter(&fallback, "info", logger.FormatJSON))
+
+	logger.FromContext(context.Background()).Info("background")
+
+	ctx := logger.WithContext(context.Background(), logger.NewWithWriter(&request, "info", logger.FormatJSON))
+	ctx = logger.With(ctx, logger.FieldUserID, "user")
+	logger.FromContext(ctx).Info("request")
+
+	background := decode(t, &fallback)
+	if len(background) != 1 || background[0]["message"] != "background" {
+		t.Errorf("default logger entries = %v, want one background entry", background)
+	}
+	entries := decode(t, &request)
+	if len(entries) != 1 || entries[0]["message"] != "request" || entries[0][logger.FieldUserID] != "user" {
+		t.Errorf("request logger entries = %v, want one request entry with user_id", entries)
+	}
+}
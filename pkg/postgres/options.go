@@ -1,6 +1,9 @@
 package postgres
 
-import "time"
+import (
+	"github.com/AhegaoHD/WBT/pkg/retry"
+	"time"
+)
 
 type Option func(*Postgres)
 
@@ -21,3 +24,11 @@ func ConnTimeout(timeout time.Duration) Option {
 		c.connTimeout = timeout
 	}
 }
+
+// TxRetry задает, сколько раз WithinTx повторяет транзакцию после deadlock или
+// serialization failure и базовую паузу между попытками.
+func TxRetry(retries int, delay time.Duration) Option {
+	return func(c *Postgres) {
+		c.txRetry = retry.Policy{Retries: retries, Delay: delay}
+	}
+}
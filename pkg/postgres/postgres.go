@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/AhegaoHD/WBT/config"
+	"github.com/AhegaoHD/WBT/pkg/retry"
 	"log"
 	"os"
 	"time"
@@ -16,6 +17,8 @@ const (
 	defaultMaxPoolSize  = 1
 	defaultConnAttempts = 10
 	defaultConnTimeout  = time.Second
+	defaultTxRetries    = 3
+	defaultTxRetryDelay = 10 * time.Millisecond
 )
 
 type Postgres struct {
@@ -24,6 +27,7 @@ type Postgres struct {
 	maxPoolSize  int
 	connAttempts int
 	connTimeout  time.Duration
+	txRetry      retry.Policy
 }
 
 func New(url string, opts ...Option) (*Postgres, error) {
@@ -31,6 +35,7 @@ func New(url string, opts ...Option) (*Postgres, error) {
 		maxPoolSize:  defaultMaxPoolSize,
 		connAttempts: defaultConnAttempts,
 		connTimeout:  defaultConnTimeout,
+		txRetry:      retry.Policy{Retries: defaultTxRetries, Delay: defaultTxRetryDelay},
 	}
 
 	// Custom options
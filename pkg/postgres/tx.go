@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier - запросы, которые выполняются одинаково в пуле и в транзакции.
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+type txKey struct{}
+
+// Querier возвращает транзакцию, открытую WithinTx, а вне транзакции - пул.
+func (p *Postgres) Querier(ctx context.Context) Querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return p.Pool
+}
+
+// WithinTx выполняет fn в транзакции, которую репозитории получают из ctx через Querier.
+// Если ctx уже содержит транзакцию, fn выполняется в точке сохранения внутри нее.
+// Ошибка или паника в fn откатывает транзакцию. Транзакция верхнего уровня, прерванная
+// из-за deadlock или serialization failure, выполняется заново, поэтому fn не должна
+// иметь побочных эффектов вне транзакции.
+func (p *Postgres) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if parent, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return runTx(ctx, parent.Begin, fn)
+	}
+	return p.txRetry.Do(ctx, func() error {
+		return runTx(ctx, p.Pool.Begin, fn)
+	})
+}
+
+func runTx(ctx context.Context, begin func(ctx context.Context) (pgx.Tx, error), fn func(ctx context.Context) error) error {
+	tx, err := begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = fn(context.WithValue(ctx, txKey{}, tx))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package random
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Rand - потокобезопасная обертка над *rand.Rand с явным seed.
+type Rand struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+	seed int64
+}
+
+// New создает генератор с заданным seed. Если seed == 0, используется текущее время.
+func New(seed int64) *Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Rand{rand: rand.New(rand.NewSource(seed)), seed: seed}
+}
+
+func (r *Rand) Seed() int64 {
+	return r.seed
+}
+
+func (r *Rand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Intn(n)
+}
+
+func (r *Rand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Float64()
+}
+
+// Derive возвращает seed n-го независимого потока игры с seed seed: один и тот же
+// (seed, n) всегда дает один и тот же результат, соседние n - несвязанные потоки.
+func Derive(seed, n int64) int64 {
+	// Перемешивание splitmix64
+	z := uint64(seed) + uint64(n)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	if z == 0 {
+		// 0 в New означает seed по времени
+		z = 1
+	}
+	return int64(z)
+}
@@ -0,0 +1,44 @@
+package requestid
+
+import (
+	"context"
+	"github.com/google/uuid"
+)
+
+// Header - заголовок, в котором клиент передает и получает идентификатор запроса.
+const Header = "X-Request-ID"
+
+// maxLength ограничивает идентификатор, пришедший от клиента.
+const maxLength = 128
+
+type ctxKey struct{}
+
+// New создает идентификатор для запроса без X-Request-ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid сообщает, можно ли принять идентификатор от клиента: непустой, не длиннее
+// maxLength и из видимых ASCII-символов, чтобы его можно было без экранирования писать в лог.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithContext кладет идентификатор запроса в контекст.
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext возвращает идентификатор запроса или пустую строку вне HTTP-запроса.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
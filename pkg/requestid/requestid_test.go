@@ -0,0 +1,49 @@
+package requestid_test
+
+import (
+	"context"
+	"github.com/AhegaoHD/WBT/pkg/requestid"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"uuid", "0b6f3c1e-8d3a-4f0e-9a57-2c1d7e5b9f10", true},
+		{"printable ascii", "req-42_~!", true},
+		{"max length", strings.Repeat("a", 128), true},
+		{"empty", "", false},
+		{"too long", strings.Repeat("a", 129), false},
+		{"space", "req 42", false},
+		{"newline", "req\n42", false},
+		{"non-ascii", "запрос", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requestid.Valid(tt.id); got != tt.want {
+				t.Errorf("Valid(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIsValid(t *testing.T) {
+	a, b := requestid.New(), requestid.New()
+	if !requestid.Valid(a) || a == b {
+		t.Errorf("New() = %q, %q, want two distinct valid ids", a, b)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if id := requestid.FromContext(context.Background()); id != "" {
+		t.Errorf("id outside a request = %q, want empty", id)
+	}
+	ctx := requestid.WithContext(context.Background(), "req-42")
+	if id := requestid.FromContext(ctx); id != "req-42" {
+		t.Errorf("id = %q, want %q", id, "req-42")
+	}
+}
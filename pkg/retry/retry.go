@@ -0,0 +1,65 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AhegaoHD/WBT/pkg/logger"
+	"github.com/jackc/pgx/v5/pgconn"
+	"math/rand"
+	"time"
+)
+
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// Retryable сообщает, что транзакция прервана из-за конфликта с параллельной
+// транзакцией и ее можно безопасно выполнить заново целиком.
+func Retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected)
+}
+
+// Policy - сколько раз повторять транзакцию после конфликта и с какой паузой.
+type Policy struct {
+	// Retries - число повторов после первой попытки, 0 - не повторять.
+	Retries int
+	// Delay - базовая пауза; перед n-м повтором ждем случайное время до Delay*2^(n-1).
+	Delay time.Duration
+}
+
+// Do выполняет fn и повторяет ее, пока fn возвращает Retryable-ошибку и не исчерпаны повторы.
+// Случайная пауза разводит столкнувшиеся транзакции, чтобы они не конфликтовали снова.
+func (p Policy) Do(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !Retryable(err) {
+			return err
+		}
+		if attempt == p.Retries {
+			if attempt > 0 {
+				return fmt.Errorf("transaction conflict after %d retries: %w", attempt, err)
+			}
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		logger.FromContext(ctx).With("attempt", attempt+1, "delay", delay).Warn("transaction conflict, retrying: %v", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff возвращает случайную паузу перед повтором номер attempt+1: от 0 до Delay*2^attempt.
+func (p Policy) backoff(attempt int) time.Duration {
+	max := p.Delay << attempt
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
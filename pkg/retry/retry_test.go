@@ -0,0 +1,112 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("boom"), false},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"lock not available", &pgconn.PgError{Code: "55P03"}, false},
+		{"wrapped deadlock", fmt.Errorf("lock loaders: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{"wrapped plain error", fmt.Errorf("lock loaders: %w", errors.New("boom")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.want {
+				t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		delay   time.Duration
+		attempt int
+		max     time.Duration
+	}{
+		{"first retry", 10 * time.Millisecond, 0, 10 * time.Millisecond},
+		{"second retry", 10 * time.Millisecond, 1, 20 * time.Millisecond},
+		{"fourth retry", 10 * time.Millisecond, 3, 80 * time.Millisecond},
+		{"no delay", 0, 2, 0},
+		{"overflow", time.Second, 62, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Policy{Retries: 5, Delay: tt.delay}
+			for i := 0; i < 1000; i++ {
+				d := p.backoff(tt.attempt)
+				if d < 0 || (tt.max == 0 && d != 0) || (tt.max > 0 && d >= tt.max) {
+					t.Fatalf("backoff(%d) = %v, want in [0, %v)", tt.attempt, d, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestDo(t *testing.T) {
+	conflict := &pgconn.PgError{Code: "40001"}
+	other := errors.New("boom")
+	tests := []struct {
+		name     string
+		retries  int
+		errs     []error
+		attempts int
+		wantErr  error
+	}{
+		{"success", 2, []error{nil}, 1, nil},
+		{"conflict then success", 2, []error{conflict, nil}, 2, nil},
+		{"non-retryable error", 2, []error{other}, 1, other},
+		{"retries exhausted", 2, []error{conflict, conflict, conflict}, 3, conflict},
+		{"no retries", 0, []error{conflict}, 1, conflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Policy{Retries: tt.retries, Delay: time.Millisecond}
+			attempts := 0
+			err := p.Do(context.Background(), func() error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+			if attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.attempts)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDoStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := Policy{Retries: 3, Delay: time.Hour}
+	attempts := 0
+	err := p.Do(ctx, func() error {
+		attempts++
+		return &pgconn.PgError{Code: "40P01"}
+	})
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+	if !Retryable(err) {
+		t.Errorf("err = %v, want the conflict", err)
+	}
+}